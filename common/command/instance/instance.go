@@ -23,6 +23,11 @@ import (
 	"github.com/queueio/sentry/utils/paths"
 	"github.com/queueio/sentry/utils/log"
 	"github.com/queueio/sentry/utils/stats"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+	"github.com/queueio/sentry/utils/outputs/route"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 var instanceDebug = log.MakeDebug("instance")
@@ -45,8 +50,11 @@ type sentryConfig struct {
 	Host      string
 	Version   string
 
+	Outputs   map[string]*config.Config `config:"outputs"`
+
 	Path      paths.Path    `config:"path"`
 	Stats    *config.Config `config:"stats"`
+	Metrics  *config.Config `config:"metrics"`
 	Log       log.Logging   `config:"log"`
 }
 
@@ -144,7 +152,7 @@ func (i *Instance) create(f component.Factory) (component.Component, error) {
 					i.Info.Component, i.Info.Version)
 
 	instanceDebug("Initializing output plugins")
-	output, err := plugin.Output(i.Info,  i.Config.Output)
+	output, err := i.output()
 	if err != nil {
 		log.Err("error initializing output: %v", err)
 	}
@@ -186,7 +194,29 @@ func (i *Instance) launch(f component.Factory) error {
 		stats.Start(i.Config.Stats, i.Info)
 	}
 
-	return sentry.Run(&i.Sentry)
+	if i.Config.Metrics.Enabled() {
+		server, err := metric.Start(i.Config.Metrics, i.Info.Component)
+		if err != nil {
+			return err
+		}
+		defer server.Stop()
+	}
+
+	if i.Config.Log.Metrics.IsEnabled() {
+		reporter := metric.StartReporter(i.Config.Log.Metrics.Interval())
+		defer reporter.Stop()
+	}
+
+	err = sentry.Run(&i.Sentry)
+
+	// The component stopped publishing, flush and close the outputs so
+	// buffered events are not lost.
+	if closer, ok := i.Handler.(io.Closer); ok {
+		if e := closer.Close(); e != nil {
+			log.Err("Failed to close output: %v", e)
+		}
+	}
+	return err
 }
 
 func (i *Instance) TestConfig(f component.Factory) error {
@@ -207,7 +237,7 @@ func (i *Instance) TestConfig(f component.Factory) error {
 	}())
 }
 
-func (i *Instance) Setup(f component.Factory) error {
+func (i *Instance) Setup(f component.Factory, options outputs.SetupOptions) error {
 	return handleError(func() error {
 		err := i.Init()
 		if err != nil {
@@ -219,10 +249,128 @@ func (i *Instance) Setup(f component.Factory) error {
 			return err
 		}
 
+		sets, err := i.outputs()
+		if err != nil {
+			return err
+		}
+
+		for _, set := range sets {
+			for name, cfg := range set {
+				if err := outputs.Setup(i.Info, name, cfg, options); err != nil {
+					return err
+				}
+			}
+		}
+
 		return nil
 	}())
 }
 
+// Replay hands the dead letters of every configured output back to it.
+func (i *Instance) Replay() error {
+	return handleError(func() error {
+		err := i.Init()
+		if err != nil {
+			return err
+		}
+
+		sets, err := i.outputs()
+		if err != nil {
+			return err
+		}
+
+		for _, set := range sets {
+			for name, cfg := range set {
+				if err := replay(i.Info, name, cfg); err != nil {
+					return err
+				}
+			}
+		}
+
+		return component.GracefulExit
+	}())
+}
+
+func replay(info component.Info, typ string, cfg *config.Config) error {
+	output := struct {
+		DeadLetter deadletter.Config `config:"dead_letter"`
+	}{deadletter.DefaultConfig}
+	if err := cfg.Unpack(&output); err != nil {
+		return err
+	}
+	if !output.DeadLetter.Enabled {
+		return nil
+	}
+
+	handler, err := plugin.Output(info, map[string]*config.Config{typ: cfg})
+	if err != nil {
+		return err
+	}
+
+	// the letters are kept under the name the output writes them with
+	letters := outputs.Name(cfg, strings.ToLower(typ))
+	n, err := deadletter.Replay(letters, output.DeadLetter, handler)
+	log.Info("Replayed %d dead letters of %s", n, letters)
+
+	// The output is opened as for shipping, for the node output with its
+	// discovery, clients and spool drain. They are stopped once the letters
+	// were handed over, whether the replay failed or not.
+	if closer, ok := handler.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// outputs returns the configuration of every output by its name. Without
+// named outputs the single output of the instance is returned.
+func (i *Instance) outputs() (map[string]map[string]*config.Config, error) {
+	if len(i.Config.Outputs) == 0 {
+		return map[string]map[string]*config.Config{"": i.Config.Output}, nil
+	}
+
+	sets := map[string]map[string]*config.Config{}
+	for name, cfg := range i.Config.Outputs {
+		set := map[string]*config.Config{}
+		if err := cfg.Unpack(&set); err != nil {
+			return nil, fmt.Errorf("error unpacking output %s: %v", name, err)
+		}
+		// outputs of the same type keep their dead letters and metrics
+		// apart by the name
+		for _, c := range set {
+			if err := c.SetString("name", -1, name); err != nil {
+				return nil, fmt.Errorf("error naming output %s: %v", name, err)
+			}
+		}
+		sets[name] = set
+	}
+	return sets, nil
+}
+
+// output creates the output of the instance. Named outputs are combined in a
+// router, so inputs can pick the outputs they publish to.
+func (i *Instance) output() (queue.Handler, error) {
+	if len(i.Config.Outputs) == 0 {
+		return plugin.Output(i.Info, i.Config.Output)
+	}
+
+	sets, err := i.outputs()
+	if err != nil {
+		return nil, err
+	}
+
+	handlers := map[string]queue.Handler{}
+	for name, set := range sets {
+		handler, err := plugin.Output(i.Info, set)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing output %s: %v", name, err)
+		}
+		handlers[name] = handler
+	}
+	return route.New(handlers), nil
+}
+
 func (i *Instance) handleFlags() error {
 	err := config.ChangeDefaultCfgFileFlag(i.Info.Component)
 	if err != nil {
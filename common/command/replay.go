@@ -0,0 +1,31 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/queueio/sentry/utils/command/instance"
+)
+
+func Replay(name, version string) *cobra.Command {
+	replay := cobra.Command{
+		Use:   "replay",
+		Short: "Replay dead letters through the outputs",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			component, err := instance.New(name, version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing sentry: %s\n", err)
+				os.Exit(1)
+			}
+
+			if err = component.Replay(); err != nil {
+				os.Exit(1)
+			}
+		},
+	}
+
+	return &replay
+}
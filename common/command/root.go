@@ -24,6 +24,7 @@ type Command struct {
 
 	Test     *cobra.Command
 	Setup    *cobra.Command
+	Replay   *cobra.Command
 	Run      *cobra.Command
 	Version  *cobra.Command
 }
@@ -38,6 +39,7 @@ func RootWithFlags(name, version string, factory component.Factory, flags *pflag
 
 	command.Run = Run(name, version, factory, flags)
 	command.Setup = Setup(name, version, factory)
+	command.Replay = Replay(name, version)
 	command.Version = Version(name, version)
 	command.Test = Test(name, version, factory)
 
@@ -65,6 +67,7 @@ func RootWithFlags(name, version string, factory component.Factory, flags *pflag
 	// Register sub commands to all component
 	command.AddCommand(command.Test)
 	command.AddCommand(command.Setup)
+	command.AddCommand(command.Replay)
 	command.AddCommand(command.Run)
 	command.AddCommand(command.Version)
 
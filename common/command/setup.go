@@ -6,14 +6,17 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/queueio/sentry/utils/outputs"
 	"github.com/queueio/sentry/utils/component"
 	"github.com/queueio/sentry/utils/command/instance"
 )
 
 func Setup(name, version string, factory component.Factory) *cobra.Command {
+	var options outputs.SetupOptions
+
 	setup := cobra.Command{
 		Use:   "setup",
-		Short: "Setup plugins",
+		Short: "Setup plugins and load index templates, policies and pipelines into the outputs",
 
 		Run: func(cmd *cobra.Command, args []string) {
 			component, err := instance.New(name, version)
@@ -22,11 +25,15 @@ func Setup(name, version string, factory component.Factory) *cobra.Command {
 				os.Exit(1)
 			}
 
-			if err = component.Setup(factory); err != nil {
+			options.Writer = cmd.OutOrStdout()
+			if err = component.Setup(factory, options); err != nil {
 				os.Exit(1)
 			}
 		},
 	}
 
+	setup.Flags().BoolVar(&options.Overwrite, "overwrite", false, "Overwrite resources which exist already")
+	setup.Flags().BoolVar(&options.DryRun, "dry-run", false, "Only print what would be loaded")
+
 	return &setup
 }
@@ -87,6 +87,18 @@ func (e *Executor) Len() uint64 {
     return uint64(len(e.tasks))
 }
 
+// Tasks returns the running tasks.
+func (e *Executor) Tasks() []Task {
+    e.RLock()
+    defer e.RUnlock()
+
+    tasks := make([]Task, 0, len(e.tasks))
+    for _, t := range e.tasks {
+        tasks = append(tasks, t)
+    }
+    return tasks
+}
+
 func (e *Executor) active() bool {
     select {
     case <-e.done:
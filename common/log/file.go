@@ -1,18 +1,23 @@
 package log
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 const RotateMaxFiles = 1024
 const DefaultKeepFiles = 7
 const DefaultLimit = 10 * 1024 * 1024
 
+const gzipExt = ".gz"
+
 type Rotate struct {
 	Path          string
 	Name          string
@@ -20,8 +25,14 @@ type Rotate struct {
 	KeepFiles    *int
 	Permissions  *uint32
 
+	// Interval rotates the file when a write crosses an interval boundary,
+	// Compress gzips the rotated files.
+	Interval      time.Duration
+	Compress      bool
+
 	current     *os.File
 	currentSize uint64
+	currentTime time.Time
 	currentLock sync.RWMutex
 }
 
@@ -103,9 +114,17 @@ func (rotate *Rotate) shouldRotate() bool {
 		return true
 	}
 
+	if rotate.Interval > 0 && !interval(time.Now(), rotate.Interval).Equal(rotate.currentTime) {
+		return true
+	}
+
 	return false
 }
 
+func interval(t time.Time, d time.Duration) time.Time {
+	return t.UTC().Truncate(d)
+}
+
 func (rotate *Rotate) FilePath(fileNo int) string {
 	if fileNo == 0 {
 		return filepath.Join(rotate.Path, rotate.Name)
@@ -115,12 +134,20 @@ func (rotate *Rotate) FilePath(fileNo int) string {
 }
 
 func (rotate *Rotate) FileExists(fileNo int) bool {
+	_, ok := rotate.existingPath(fileNo)
+	return ok
+}
+
+// existingPath returns the path of the file, compressed or not.
+func (rotate *Rotate) existingPath(fileNo int) (string, bool) {
 	path := rotate.FilePath(fileNo)
-	_, err := os.Stat(path)
-	if os.IsNotExist(err) {
-		return false
+	if _, err := os.Stat(path); err == nil {
+		return path, true
+	}
+	if _, err := os.Stat(path + gzipExt); err == nil {
+		return path + gzipExt, true
 	}
-	return true
+	return path, false
 }
 
 func (rotate *Rotate) Rotate() error {
@@ -135,8 +162,8 @@ func (rotate *Rotate) Rotate() error {
 
 	// delete any extra files, normally we shouldn't have any
 	for fileNo := *rotate.KeepFiles; fileNo < RotateMaxFiles; fileNo++ {
-		if rotate.FileExists(fileNo) {
-			perr := os.Remove(rotate.FilePath(fileNo))
+		if path, ok := rotate.existingPath(fileNo); ok {
+			perr := os.Remove(path)
 			if perr != nil {
 				return perr
 			}
@@ -144,16 +171,20 @@ func (rotate *Rotate) Rotate() error {
 	}
 
 	for fileNo := *rotate.KeepFiles - 1; fileNo >= 0; fileNo-- {
-		if !rotate.FileExists(fileNo) {
+		path, ok := rotate.existingPath(fileNo)
+		if !ok {
 			continue
 		}
-		path := rotate.FilePath(fileNo)
 
 		if rotate.FileExists(fileNo + 1) {
 			return fmt.Errorf("file %s exists, when rotating would overwrite it", rotate.FilePath(fileNo+1))
 		}
 
-		err := os.Rename(path, rotate.FilePath(fileNo+1))
+		ext := ""
+		if strings.HasSuffix(path, gzipExt) {
+			ext = gzipExt
+		}
+		err := os.Rename(path, rotate.FilePath(fileNo+1)+ext)
 		if err != nil {
 			return err
 		}
@@ -166,13 +197,69 @@ func (rotate *Rotate) Rotate() error {
 	}
 	rotate.current = current
 	rotate.currentSize = 0
+	rotate.currentTime = interval(time.Now(), rotate.Interval)
 
-	path = rotate.FilePath(*rotate.KeepFiles)
-	os.Remove(path)
+	if path, ok := rotate.existingPath(*rotate.KeepFiles); ok {
+		os.Remove(path)
+	}
+
+	if rotate.Compress {
+		if err := rotate.compress(rotate.FilePath(1)); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// compress replaces the rotated file with its gzipped copy.
+func (rotate *Rotate) compress(path string) error {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + gzipExt + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(rotate.getPermissions()))
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(out)
+	if _, err = io.Copy(writer, in); err == nil {
+		err = writer.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+gzipExt); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (rotate *Rotate) Close() error {
+	rotate.currentLock.Lock()
+	defer rotate.currentLock.Unlock()
+
+	if rotate.current == nil {
+		return nil
+	}
+
+	err := rotate.current.Close()
+	rotate.current = nil
+	return err
+}
+
 func (rotate *Rotate) getPermissions() uint32 {
 	if rotate.Permissions == nil {
 		return 0600
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"time"
 )
@@ -76,17 +77,27 @@ func debugMessage(calldepth int, selector, format string, v ...interface{}) {
 }
 
 func send(calldepth int, level Priority, prefix string, format string, v ...interface{}) {
-	message := fmt.Sprintf(format, v...)
+	write(calldepth+1, level, prefix, fmt.Sprintf(format, v...), nil)
+}
+
+// write outputs the message with its fields. In JSON mode the fields become
+// keys of the line, otherwise they are appended as sorted key=value pairs.
+func write(calldepth int, level Priority, prefix string, message string, fields map[string]interface{}) {
 	timestamp := time.Now().Format(time.RFC3339)
 	var bytes []byte
 	if _log.JSON {
-		log := map[string]interface{}{
-			"timestamp": timestamp,
-			"level":     prefix,
-			"message":   message,
+		log := map[string]interface{}{}
+		for k, v := range fields {
+			log[k] = v
 		}
+		log["timestamp"] = timestamp
+		log["level"] = prefix
+		log["message"] = message
 		bytes, _ = json.Marshal(log)
 	} else {
+		if len(fields) > 0 {
+			message = message + " " + strings.Join(pairs("", fields), " ")
+		}
 		bytes = []byte(fmt.Sprintf("%s %s %s", timestamp, prefix, message))
 	}
 
@@ -116,6 +127,26 @@ func send(calldepth int, level Priority, prefix string, format string, v ...inte
 	}
 }
 
+// pairs formats the fields as sorted key=value pairs, nested maps with their
+// keys joined by dots.
+func pairs(prefix string, fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var result []string
+	for _, k := range keys {
+		if nested, ok := fields[k].(map[string]interface{}); ok {
+			result = append(result, pairs(prefix+k+".", nested)...)
+			continue
+		}
+		result = append(result, fmt.Sprintf("%s%s=%v", prefix, k, fields[k]))
+	}
+	return result
+}
+
 func Debug(selector string, format string, v ...interface{}) {
 	debugMessage(3, selector, format, v...)
 }
@@ -145,6 +176,13 @@ func Info(format string, v ...interface{}) {
 	msg(LOG_INFO, "INFO", format, v...)
 }
 
+// InfoFields logs the message at INFO level along with structured fields.
+func InfoFields(message string, fields map[string]interface{}) {
+	if _log.level >= LOG_INFO {
+		write(3, LOG_INFO, "INFO", message, fields)
+	}
+}
+
 func Warn(format string, v ...interface{}) {
 	msg(LOG_WARNING, "WARN", format, v...)
 }
@@ -28,6 +28,29 @@ type Logging struct {
 
 	Level      string
 	Json       bool
+	Metrics    Metrics
+}
+
+// Metrics configures the periodic log line of the metrics which changed.
+type Metrics struct {
+	Enabled   *bool
+	Period    time.Duration
+}
+
+// DefaultMetricsPeriod is used when no period is configured.
+const DefaultMetricsPeriod = 30 * time.Second
+
+// IsEnabled tells whether metrics are logged, they are unless disabled.
+func (m *Metrics) IsEnabled() bool {
+	return m.Enabled == nil || *m.Enabled
+}
+
+// Interval returns the configured period, or DefaultMetricsPeriod.
+func (m *Metrics) Interval() time.Duration {
+	if m.Period <= 0 {
+		return DefaultMetricsPeriod
+	}
+	return m.Period
 }
 
 func init() {
@@ -0,0 +1,117 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+)
+
+var ValidCompression = map[string]byte{
+	CompressionNone:   0,
+	CompressionGzip:   1,
+	CompressionSnappy: 2,
+}
+
+// A batch packs encoded events into one queue message:
+//
+//	magic (4 bytes) | version (1 byte) | compression (1 byte) | payload
+//
+// The payload, compressed as the compression byte says, is a sequence of
+// uvarint length prefixed events. The magic tells batches apart from single
+// events in any codec.
+var batchMagic = []byte{0xf5, 'B', 'A', 'T'}
+
+const batchVersion = 1
+
+var ErrBatch = errors.New("invalid batch")
+
+// IsBatch reports whether the body is a batch made by Pack.
+func IsBatch(body []byte) bool {
+	return bytes.HasPrefix(body, batchMagic)
+}
+
+// Pack puts the encoded events into one batch.
+func Pack(bodies [][]byte, compression string) ([]byte, error) {
+	c, ok := ValidCompression[compression]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression %v", compression)
+	}
+
+	var payload []byte
+	var size [binary.MaxVarintLen64]byte
+	for _, body := range bodies {
+		n := binary.PutUvarint(size[:], uint64(len(body)))
+		payload = append(payload, size[:n]...)
+		payload = append(payload, body...)
+	}
+
+	buf := append(append([]byte{}, batchMagic...), batchVersion, c)
+	switch compression {
+	case CompressionGzip:
+		w := bytes.NewBuffer(buf)
+		writer := gzip.NewWriter(w)
+		if _, err := writer.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	case CompressionSnappy:
+		return append(buf, snappy.Encode(nil, payload)...), nil
+	default:
+		return append(buf, payload...), nil
+	}
+}
+
+// Unpack returns the encoded events of a batch.
+func Unpack(body []byte) ([][]byte, error) {
+	header := len(batchMagic) + 2
+	if !IsBatch(body) || len(body) < header {
+		return nil, ErrBatch
+	}
+	if body[len(batchMagic)] != batchVersion {
+		return nil, fmt.Errorf("unsupported batch version %d", body[len(batchMagic)])
+	}
+
+	payload := body[header:]
+	var err error
+	switch body[len(batchMagic)+1] {
+	case ValidCompression[CompressionNone]:
+	case ValidCompression[CompressionGzip]:
+		var reader *gzip.Reader
+		if reader, err = gzip.NewReader(bytes.NewReader(payload)); err == nil {
+			payload, err = ioutil.ReadAll(reader)
+		}
+	case ValidCompression[CompressionSnappy]:
+		payload, err = snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("unknown batch compression %d", body[len(batchMagic)+1])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bodies [][]byte
+	for len(payload) > 0 {
+		n, size := binary.Uvarint(payload)
+		if size <= 0 || uint64(len(payload)-size) < n {
+			return nil, ErrBatch
+		}
+		payload = payload[size:]
+		bodies = append(bodies, payload[:n:n])
+		payload = payload[n:]
+	}
+	return bodies, nil
+}
@@ -0,0 +1,78 @@
+// Package codec serializes events for the outputs. Outputs pick a codec in
+// their config:
+//
+//	codec:
+//	  type: json      # json, format, msgpack or protobuf
+//	  pretty: false   # json only
+//	  format: ""      # format only, see types/format
+//
+// Events published by the node output are decoded by Decode, whatever the
+// node codec was, except format which is text for humans.
+package codec
+
+import (
+	"fmt"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/format"
+)
+
+type Codec interface {
+	Encode(e *event.Event) ([]byte, error)
+}
+
+type Config struct {
+	Type   string         `config:"type"`
+	Pretty bool           `config:"pretty"`
+	Format *format.String `config:"format"`
+}
+
+var DefaultConfig = Config{
+	Type: "json",
+}
+
+type Factory func(config Config) (Codec, error)
+
+var codecs = map[string]Factory{}
+
+func Register(name string, f Factory) {
+	if codecs[name] != nil {
+		panic(fmt.Errorf("codec '%v' exists already", name))
+	}
+	codecs[name] = f
+}
+
+func New(config Config) (Codec, error) {
+	factory := codecs[config.Type]
+	if factory == nil {
+		return nil, fmt.Errorf("codec %v undefined", config.Type)
+	}
+	return factory(config)
+}
+
+func (c *Config) Validate() error {
+	if codecs[c.Type] == nil {
+		return fmt.Errorf("Invalid codec: %v", c.Type)
+	}
+	return nil
+}
+
+// Decode reads an event encoded by the json, msgpack or protobuf codec,
+// telling them apart by the first byte.
+func Decode(body []byte) (*event.Event, error) {
+	for i, b := range body {
+		switch {
+		case b == ' ' || b == '\t' || b == '\r' || b == '\n':
+			continue
+		case b == '{':
+			return decodeJSON(body[i:])
+		case isMsgpackMap(b):
+			return decodeMsgpack(body)
+		case b == envelopeTag:
+			return decodeProtobuf(body)
+		default:
+			return nil, fmt.Errorf("unknown event encoding, first byte 0x%02x", b)
+		}
+	}
+	return nil, fmt.Errorf("empty event")
+}
@@ -0,0 +1,25 @@
+package codec
+
+import (
+	"fmt"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/format"
+)
+
+func init() {
+	Register("format", func(config Config) (Codec, error) {
+		if config.Format == nil {
+			return nil, fmt.Errorf("format codec requires a format string")
+		}
+		return &formatCodec{format: config.Format}, nil
+	})
+}
+
+type formatCodec struct {
+	format *format.String
+}
+
+func (c *formatCodec) Encode(e *event.Event) ([]byte, error) {
+	return c.format.Append(nil, e)
+}
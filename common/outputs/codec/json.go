@@ -0,0 +1,41 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+func init() {
+	Register("json", func(config Config) (Codec, error) {
+		return &jsonCodec{pretty: config.Pretty}, nil
+	})
+}
+
+type jsonCodec struct {
+	pretty bool
+}
+
+func (c *jsonCodec) Encode(e *event.Event) ([]byte, error) {
+	if c.pretty {
+		return json.MarshalIndent(e, "", "  ")
+	}
+	return json.Marshal(e)
+}
+
+func decodeJSON(body []byte) (*event.Event, error) {
+	var e event.Event
+	if err := unmarshalNumbers(body, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// unmarshalNumbers keeps the numbers as they were, so integers stay
+// integers.
+func unmarshalNumbers(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
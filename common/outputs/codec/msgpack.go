@@ -0,0 +1,56 @@
+package codec
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/vmihailenco/msgpack"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+func init() {
+	Register("msgpack", func(config Config) (Codec, error) {
+		return &msgpackCodec{}, nil
+	})
+}
+
+type msgpackCodec struct{}
+
+// msgpackEvent is the map the msgpack codec writes.
+type msgpackEvent struct {
+	Topic     string                 `msgpack:"topic"`
+	Timestamp time.Time              `msgpack:"@timestamp"`
+	Meta      map[string]interface{} `msgpack:"meta,omitempty"`
+	Fields    map[string]interface{} `msgpack:"fields"`
+}
+
+func (c *msgpackCodec) Encode(e *event.Event) ([]byte, error) {
+	return msgpack.Marshal(&msgpackEvent{
+		Topic:     e.Topic,
+		Timestamp: e.Timestamp,
+		Meta:      e.Meta,
+		Fields:    e.Fields,
+	})
+}
+
+func isMsgpackMap(b byte) bool {
+	return b&0xf0 == 0x80 || b == 0xde || b == 0xdf
+}
+
+func decodeMsgpack(body []byte) (*event.Event, error) {
+	var m msgpackEvent
+	decoder := msgpack.NewDecoder(bytes.NewReader(body))
+	decoder.UseDecodeInterfaceLoose(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return &event.Event{
+		Topic:     m.Topic,
+		Timestamp: m.Timestamp,
+		Meta:      maps.StringIf(m.Meta),
+		Fields:    maps.StringIf(m.Fields),
+	}, nil
+}
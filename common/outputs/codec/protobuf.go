@@ -0,0 +1,125 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+// The protobuf codec writes this envelope, meta and fields being JSON
+// objects so the schema never changes with the events:
+//
+//	message Event {
+//	  uint32 version   = 1; // always 1, written first
+//	  string topic     = 2;
+//	  int64  timestamp = 3; // unix nanoseconds
+//	  bytes  meta      = 4;
+//	  bytes  fields    = 5;
+//	}
+const (
+	envelopeVersion = 1
+	envelopeTag     = byte(1<<3 | protowire.VarintType)
+)
+
+func init() {
+	Register("protobuf", func(config Config) (Codec, error) {
+		return &protobufCodec{}, nil
+	})
+}
+
+type protobufCodec struct{}
+
+func (c *protobufCodec) Encode(e *event.Event) ([]byte, error) {
+	fields, err := json.Marshal(e.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, envelopeVersion)
+	if e.Topic != "" {
+		buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+		buf = protowire.AppendString(buf, e.Topic)
+	}
+	if !e.Timestamp.IsZero() {
+		buf = protowire.AppendTag(buf, 3, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(e.Timestamp.UnixNano()))
+	}
+	if len(e.Meta) > 0 {
+		meta, err := json.Marshal(e.Meta)
+		if err != nil {
+			return nil, err
+		}
+		buf = protowire.AppendTag(buf, 4, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, meta)
+	}
+	buf = protowire.AppendTag(buf, 5, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, fields)
+	return buf, nil
+}
+
+func decodeProtobuf(body []byte) (*event.Event, error) {
+	e := &event.Event{}
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		body = body[n:]
+
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			version, n := protowire.ConsumeVarint(body)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if version != envelopeVersion {
+				return nil, fmt.Errorf("unsupported envelope version %d", version)
+			}
+			body = body[n:]
+		case num == 2 && typ == protowire.BytesType:
+			topic, n := protowire.ConsumeString(body)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			e.Topic = topic
+			body = body[n:]
+		case num == 3 && typ == protowire.VarintType:
+			ts, n := protowire.ConsumeVarint(body)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			e.Timestamp = time.Unix(0, int64(ts)).UTC()
+			body = body[n:]
+		case (num == 4 || num == 5) && typ == protowire.BytesType:
+			b, n := protowire.ConsumeBytes(body)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			var m maps.StringIf
+			if err := unmarshalNumbers(b, &m); err != nil {
+				return nil, err
+			}
+			if num == 4 {
+				e.Meta = m
+			} else {
+				e.Fields = m
+			}
+			body = body[n:]
+		default:
+			// skip fields of newer envelopes
+			n := protowire.ConsumeFieldValue(num, typ, body)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			body = body[n:]
+		}
+	}
+	return e, nil
+}
@@ -1,8 +1,18 @@
 package console
 
+import (
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+)
+
 type Config struct {
-	Pretty bool `config:"pretty"`
+	Pretty bool `config:"pretty"` // shorthand for the pretty json codec
 	Batch  int
+	Codec  codec.Config `config:"codec"`
+	DeadLetter deadletter.Config `config:"dead_letter"`
 }
 
-var defaultConfig = Config{}
+var defaultConfig = Config{
+	Codec:      codec.DefaultConfig,
+	DeadLetter: deadletter.DefaultConfig,
+}
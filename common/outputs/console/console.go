@@ -4,23 +4,32 @@ import (
 	"os"
 	"fmt"
 	"bufio"
+	"sync"
 	"runtime"
+	"time"
 
 	"github.com/queueio/sentry/utils/log"
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/config"
 	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
 	"github.com/queueio/sentry/utils/component"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 var consoleDebug = log.MakeDebug("console")
 
 type console struct {
+	sync.Mutex
 	name    string
 	stdout *os.File
 	writer *bufio.Writer
 	buffer  int
 	end     byte
+	codec   codec.Codec
+	dead   *deadletter.DeadLetter
+	metrics *metric.Output
 }
 
 func init() {
@@ -28,19 +37,35 @@ func init() {
 }
 
 func open(info component.Info, config *config.Config) (queue.Handler, error) {
-	/*
 	cfg := defaultConfig
-	err := config.Unpack(&config)
+	err := config.Unpack(&cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	if cfg.Pretty && cfg.Codec.Type == "json" {
+		cfg.Codec.Pretty = true
+	}
+
+	encoder, err := codec.New(cfg.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	name := outputs.Name(config, "console")
+	dead, err := deadletter.New(name, cfg.DeadLetter)
 	if err != nil {
 		return outputs.Fail(err)
 	}
-	*/
 
 	c := &console{
 		name: info.Component,
         stdout: os.Stdout,
         buffer: 8*1024,
         end: '\n',
+        codec: encoder,
+        dead: dead,
+        metrics: metric.NewOutput(name),
     }
 
 	// check stdout actually being available
@@ -55,19 +80,59 @@ func open(info component.Info, config *config.Config) (queue.Handler, error) {
 	return c, nil
 }
 
-func (c *console) Close() error { return nil }
+func (c *console) Close() error {
+	return c.dead.Close()
+}
 
+// HandleMessage writes the events in the console codec to stdout. Write
+// errors are returned, so the queue delivers the message again.
 func (c *console) HandleMessage(message *queue.Message) error {
-	fmt.Println(string(message.Body))
-	/*
-    err := client(c.writer, message.Body, c.end)
-    if err != nil {
-		return err
+	messages, err := outputs.Unpack(message)
+	if err != nil {
+		c.metrics.Dropped.Inc()
+		c.dead.Message(message, err)
+		return nil
 	}
-	*/
 
+	for _, m := range messages {
+		c.metrics.Published.Inc()
+		event, err := codec.Decode(m.Body)
+		if err == nil {
+			var data []byte
+			if data, err = c.codec.Encode(event); err == nil {
+				start := time.Now()
+				err := c.write(data)
+				c.metrics.Latency.Since(start)
+				if err != nil {
+					c.metrics.Failed.Inc()
+					return err
+				}
+				c.metrics.Acked.Inc()
+				continue
+			}
+		}
+
+		c.metrics.Dropped.Inc()
+		c.dead.Message(m, err)
+	}
 	return nil
 }
 
+func (c *console) write(data []byte) error {
+	c.Lock()
+	defer c.Unlock()
+
+	err := client(c.writer, data, c.end)
+	if err != nil {
+		consoleDebug("Writing message failed: %v", err)
+		c.writer.Reset(c.stdout)
+		return err
+	}
+
+	return c.writer.Flush()
+}
+
 func (c *console) LogFailedMessage(message *queue.Message) {
+	c.metrics.Dropped.Inc()
+	c.dead.Message(message, outputs.ErrAttempts)
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+package deadletter
+
+import "fmt"
+
+const (
+	TypeFile  = "file"
+	TypeQueue = "queue"
+)
+
+type Config struct {
+	Enabled bool   `config:"enabled"`
+	Type    string `config:"type"`
+
+	// file sink, rotated below <paths.data>/<path>/<output>
+	Path      string `config:"path"`
+	Limit     uint64 `config:"limit" validate:"min=1"`
+	KeepFiles int    `config:"keepfiles"`
+
+	// queue sink
+	Address string `config:"address"`
+	Topic   string `config:"topic"`
+}
+
+var DefaultConfig = Config{
+	Type:      TypeFile,
+	Path:      "dead_letter",
+	Limit:     10 * 1024 * 1024,
+	KeepFiles: 7,
+}
+
+var ValidType = map[string]struct{}{
+	TypeFile:  {},
+	TypeQueue: {},
+}
+
+func (c *Config) Validate() error {
+	if _, ok := ValidType[c.Type]; !ok {
+		return fmt.Errorf("Invalid dead letter type: %v", c.Type)
+	}
+
+	if c.Type == TypeQueue && (c.Address == "" || c.Topic == "") {
+		return fmt.Errorf("Dead letter queue requires address and topic")
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package deadletter
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/paths"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+const fileName = "dead_letter"
+
+// Record is what the dead-letter sink stores for every event an output gave
+// up on, one JSON document per line. Events in a binary codec are kept
+// base64 encoded in Body.
+type Record struct {
+	Timestamp time.Time       `json:"@timestamp"`
+	Output    string          `json:"output"`
+	Error     string          `json:"error"`
+	ID        string          `json:"id,omitempty"`
+	Event     json.RawMessage `json:"event,omitempty"`
+	Body      []byte          `json:"body,omitempty"`
+}
+
+type writer interface {
+	write(line []byte) error
+	close() error
+}
+
+// DeadLetter records the events of one output. A nil DeadLetter only logs
+// the failures, so outputs can use it whether the sink is enabled or not.
+type DeadLetter struct {
+	sync.Mutex
+	output string
+	writer writer
+}
+
+// New opens the dead-letter sink of the output, nil if it is disabled.
+func New(output string, config Config) (*DeadLetter, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	var w writer
+	var err error
+	switch config.Type {
+	case TypeQueue:
+		w, err = newQueueWriter(config)
+	default:
+		w, err = newFileWriter(Path(output, config), config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetter{output: output, writer: w}, nil
+}
+
+// Path returns the directory of the dead-letter files of the output.
+func Path(output string, config Config) string {
+	return paths.Resolve(paths.Data, filepath.Join(config.Path, output))
+}
+
+// Message records a queue message the output failed to deliver.
+func (d *DeadLetter) Message(message *queue.Message, cause error) error {
+	record := Record{ID: hex.EncodeToString(message.ID[:])}
+	if json.Valid(message.Body) {
+		record.Event = json.RawMessage(message.Body)
+	} else {
+		record.Body = message.Body
+	}
+	return d.write(record, cause)
+}
+
+// Event records an event the output failed to deliver.
+func (d *DeadLetter) Event(e event.Event, cause error) error {
+	e.Private = nil
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return d.write(Record{Event: body}, cause)
+}
+
+func (d *DeadLetter) write(record Record, cause error) error {
+	if d == nil {
+		log.Err("Dropping undeliverable event: %v", cause)
+		return nil
+	}
+
+	record.Timestamp = time.Now().UTC()
+	record.Output = d.output
+	record.Error = cause.Error()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	if err := d.writer.write(line); err != nil {
+		log.Err("Writing dead letter of %s failed: %v", d.output, err)
+		return err
+	}
+	return nil
+}
+
+func (d *DeadLetter) Close() error {
+	if d == nil {
+		return nil
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	return d.writer.close()
+}
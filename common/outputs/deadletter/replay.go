@@ -0,0 +1,173 @@
+package deadletter
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/queue"
+)
+
+const replayDir = "replay"
+
+var ErrReplayQueue = errors.New("dead letters in a queue topic are replayed by consuming the topic")
+
+// Replay hands the recorded events of the output back to handler, oldest
+// first. The dead-letter files are moved aside before, so events the output
+// fails on asynchronously are recorded anew instead of being read twice.
+// Replay stops at the first event handler returns an error for and keeps it
+// and all following events for the next replay. The output should not be
+// running elsewhere while replaying, the caller closes it afterwards to
+// flush the events it batches.
+func Replay(output string, config Config, handler queue.Handler) (int, error) {
+	if config.Type == TypeQueue {
+		return 0, ErrReplayQueue
+	}
+
+	files, err := moveAside(Path(output, config))
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, file := range files {
+		n, err := replayFile(file, handler)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+
+		if err := os.Remove(file); err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
+
+// moveAside moves the rotated files below the replay directory and returns
+// all files waiting there, oldest first.
+func moveAside(path string) ([]string, error) {
+	dir := filepath.Join(path, replayDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixNano()
+	for n, i := 0, log.RotateMaxFiles; i >= 0; i-- {
+		name := fileName
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d", fileName, i)
+		}
+
+		err := os.Rename(filepath.Join(path, name), filepath.Join(dir, fmt.Sprintf("%d.%04d", now, n)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		n++
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func replayFile(file string, handler queue.Handler) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	replayed := 0
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var record Record
+			if err := json.Unmarshal(line, &record); err != nil {
+				log.Err("Skipping invalid dead letter in %s: %v", file, err)
+			} else if err := replay(record, handler); err != nil {
+				if err := keep(file, line, reader); err != nil {
+					return replayed, err
+				}
+				return replayed, fmt.Errorf("Replaying dead letter failed: %v", err)
+			} else {
+				replayed++
+			}
+		}
+
+		if err == io.EOF {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, err
+		}
+	}
+}
+
+// keep replaces the file with the failed line and the lines not read yet.
+func keep(file string, line []byte, rest io.Reader) error {
+	tmp := filepath.Join(filepath.Dir(file), "."+filepath.Base(file))
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(line); err == nil {
+		_, err = io.Copy(f, rest)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+func replay(record Record, handler queue.Handler) error {
+	message := &queue.Message{
+		Body:      record.Event,
+		Timestamp: time.Now().UnixNano(),
+	}
+	if len(message.Body) == 0 {
+		message.Body = record.Body
+	}
+
+	if id, err := hex.DecodeString(record.ID); err == nil && len(id) == len(message.ID) {
+		copy(message.ID[:], id)
+	} else {
+		var id [len(message.ID) / 2]byte
+		rand.Read(id[:])
+		hex.Encode(message.ID[:], id[:])
+	}
+
+	return handler.HandleMessage(message)
+}
@@ -0,0 +1,59 @@
+package deadletter
+
+import (
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/queue"
+)
+
+type fileWriter struct {
+	rotate *log.Rotate
+}
+
+func newFileWriter(path string, config Config) (*fileWriter, error) {
+	rotate := &log.Rotate{
+		Path:      path,
+		Name:      fileName,
+		Limit:     &config.Limit,
+		KeepFiles: &config.KeepFiles,
+	}
+
+	if err := rotate.CreateDirectory(); err != nil {
+		return nil, err
+	}
+	if err := rotate.CheckIfConfigSane(); err != nil {
+		return nil, err
+	}
+
+	return &fileWriter{rotate: rotate}, nil
+}
+
+func (w *fileWriter) write(line []byte) error {
+	return w.rotate.WriteLine(line)
+}
+
+func (w *fileWriter) close() error {
+	return w.rotate.Close()
+}
+
+type queueWriter struct {
+	topic    string
+	producer *queue.Producer
+}
+
+func newQueueWriter(config Config) (*queueWriter, error) {
+	producer, err := queue.NewProducer(config.Address, queue.NewConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return &queueWriter{topic: config.Topic, producer: producer}, nil
+}
+
+func (w *queueWriter) write(line []byte) error {
+	return w.producer.Publish(w.topic, line)
+}
+
+func (w *queueWriter) close() error {
+	w.producer.Stop()
+	return nil
+}
@@ -1,54 +1,67 @@
 package elasticsearch
 
 import (
-	"time"
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/olivere/elastic"
-	"github.com/queueio/sentry/utils/log"
-	"fmt"
+
+	"github.com/queueio/sentry/utils/queue"
 )
 
-var ErrBulkCommit = errors.New("bulk commit failed")
-
-type bulk struct {
-	size     int
-	timeout  int64
-    service  map[string]*service
-}
-
-type service struct {
-	timestamp  int64  // record last time
-    service   *elastic.BulkService
-}
-
-func (b *bulk) flush() error {
-	var error error = nil
-
-	current := time.Now().Unix()
-	for k, v := range b.service {
-		if time := current - v.timestamp; time > b.timeout {
-			if v.service.NumberOfActions() > 0 {
-				response, err := v.service.Do()
-				if err != nil {
-					error = err
-					log.Err("key is: %s, error: %s", k, err.Error())
-					continue
-				}
-
-				if response.Errors {
-					error = ErrBulkCommit
-					log.Err("key is: %s, error: %s", k, ErrBulkCommit.Error())
-					continue
-				}
-
-				fmt.Println(response.Items)
-			}
-		} else {
-			log.Debug("bulk", "The current time minus " +
-						"the past time result is: %d", time)
-		}
+var (
+	ErrBulkCommit = errors.New("bulk commit failed")
+	ErrNoIndex    = errors.New("event has no index")
+)
+
+type document struct {
+	id       string
+	message *queue.Message
+	request  elastic.BulkableRequest
+}
+
+// batch collects the documents of one index until they are committed.
+type batch struct {
+	index      string
+	documents []*document
+	bytes      int
+	timestamp  time.Time // first document added
+}
+
+func (b *batch) add(d *document) {
+	if len(b.documents) == 0 {
+		b.timestamp = time.Now()
 	}
+	b.documents = append(b.documents, d)
+	b.bytes += len(d.message.Body)
+}
+
+func (b *batch) full(config bulkConfig) bool {
+	return len(b.documents) >= config.Size || (config.Bytes > 0 && b.bytes >= config.Bytes)
+}
 
-	return error
-}
\ No newline at end of file
+func (b *batch) expired(timeout time.Duration) bool {
+	return len(b.documents) > 0 && time.Since(b.timestamp) >= timeout
+}
+
+func (b *batch) take() []*document {
+	documents := b.documents
+	b.documents = nil
+	b.bytes = 0
+	return documents
+}
+
+// retryable reports whether indexing a document rejected with status may
+// succeed later.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+func itemError(item *elastic.BulkResponseItem) error {
+	if item.Error == nil {
+		return fmt.Errorf("status %d", item.Status)
+	}
+	return fmt.Errorf("status %d, %s: %s", item.Status, item.Error.Type, item.Error.Reason)
+}
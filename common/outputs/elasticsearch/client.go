@@ -1,8 +1,10 @@
 package elasticsearch
 
 import (
+	"fmt"
+	"sync"
+	"strings"
 	"time"
-	"encoding/json"
 
 	"github.com/satori/go.uuid"
 	"github.com/olivere/elastic"
@@ -10,69 +12,282 @@ import (
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/log"
 	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
 	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/format"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 type Client struct {
 	id        uuid.UUID
 	channel  *chan *queue.Message
 	producer *elastic.Client
-	bulk     *bulk
+	index    *format.String
+	pipeline *format.String
+	docType   string
+	stream    bool
+	bulk      bulkConfig
+	retry     retryConfig
+	dead     *deadletter.DeadLetter
+	metrics  *metric.Output
+
+	batches   map[string]*batch
+	done      chan struct{}
+	stopOnce  sync.Once
 }
 
 func (c *Client) ID() uuid.UUID {
 	return c.id
 }
 
+// Run restarts the worker until the client is stopped. Documents batched
+// before a failure are kept for the next run.
 func (c *Client) Run() error {
-    for {
-        select {
-        case msg, open := <-*c.channel:
-        	if !open {
-        		c.Stop()
-                return outputs.ErrClosed
-            }
-
-            var event event.Event
-            if err := json.Unmarshal(msg.Body, &event); err != nil {
-            	log.Err("json unmarshal error: %s", err.Error())
-            	continue
-			}
+	defer c.producer.Stop()
 
-			key := event.Topic
-			if bulk, ok := c.bulk.service[key]; ok {
-				bulk.service.Add(elastic.NewBulkIndexRequest().Id(string(msg.ID[:])).Doc(event.Fields))
-				if bulk.service.NumberOfActions() >= c.bulk.size {
-					response, err := bulk.service.Do()
-					if err != nil {
-						return err
-					}
-					if response.Errors {
-						return ErrBulkCommit
-					}
-
-					log.Debug("client", "elasticSearch bulk success")
-				}
-
-				bulk.timestamp = time.Now().Unix()
-			} else {
-				c.bulk.service[key] = &service{
-					timestamp: time.Now().Unix(),
-					service: c.producer.Bulk().Index(key).Type(docType),
-				}
-				log.Debug("client", "elasticSearch bulk index create")
-			}
+	backoff := c.retry.Backoff.Init
+	for {
+		err := c.run()
+		if err == nil || err == outputs.ErrClosed {
+			return err
+		}
+
+		log.Err("Elasticsearch worker failed: %v. Restarting in %v", err, backoff)
+		select {
+		case <-c.done:
+			c.flush(false)
+			return nil
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > c.retry.Backoff.Max {
+			backoff = c.retry.Backoff.Max
+		}
+	}
+}
+
+func (c *Client) run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ticker := time.NewTicker(c.tick())
+	defer ticker.Stop()
 
-		default:
-			if err := c.bulk.flush(); err != nil {
-				log.Err("flush error: %s", err.Error())
+	for {
+		select {
+		case <-c.done:
+			c.flush(false)
+			return nil
+		case msg, open := <-*c.channel:
+			if !open {
+				c.flush(false)
+				return outputs.ErrClosed
 			}
-        }
-    }
 
-	return nil
+			c.add(msg)
+		case <-ticker.C:
+			c.flush(true)
+		}
+	}
+}
+
+// tick checks for expired batches often enough to flush them at most a
+// tenth of the timeout late.
+func (c *Client) tick() time.Duration {
+	if tick := c.bulk.Timeout / 10; tick >= 10*time.Millisecond {
+		return tick
+	}
+	return 10 * time.Millisecond
+}
+
+func (c *Client) add(msg *queue.Message) {
+	event, err := codec.Decode(msg.Body)
+	if err != nil {
+		log.Err("decoding event failed: %s", err.Error())
+		c.metrics.Dropped.Inc()
+		c.dead.Message(msg, err)
+		outputs.Done(msg)
+		return
+	}
+
+	request, index, err := c.request(msg, event)
+	if err != nil {
+		log.Err("Cannot index event of topic %s: %v", event.Topic, err)
+		c.metrics.Dropped.Inc()
+		c.dead.Message(msg, err)
+		outputs.Done(msg)
+		return
+	}
+
+	b, ok := c.batches[index]
+	if !ok {
+		b = &batch{index: index}
+		c.batches[index] = b
+		log.Debug("client", "elasticSearch bulk index create")
+	}
+
+	b.add(&document{
+		id:      string(msg.ID[:]),
+		message: msg,
+		request: request,
+	})
+
+	if b.full(c.bulk) {
+		c.commit(b)
+	}
+}
+
+// request builds the bulk request of the event and returns it along with
+// the index it goes to.
+func (c *Client) request(msg *queue.Message, event *event.Event) (*elastic.BulkIndexRequest, string, error) {
+	index := event.Topic
+	if c.index != nil {
+		var err error
+		if index, err = c.index.Run(event); err != nil {
+			return nil, "", err
+		}
+	}
+	if index == "" {
+		return nil, "", ErrNoIndex
+	}
+	index = strings.ToLower(index)
+
+	doc := event.Fields
+	request := elastic.NewBulkIndexRequest().Index(index).Id(string(msg.ID[:]))
+	if c.stream {
+		// data streams only take creates of documents with a timestamp
+		request.OpType("create")
+		if _, ok := doc["@timestamp"]; !ok {
+			doc = doc.Clone()
+			doc["@timestamp"] = event.Timestamp
+		}
+	} else if c.docType != "" {
+		request.Type(c.docType)
+	}
+
+	if c.pipeline != nil {
+		pipeline, err := c.pipeline.Run(event)
+		if err != nil {
+			return nil, "", err
+		}
+		if pipeline != "" {
+			request.Pipeline(pipeline)
+		}
+	}
+
+	return request.Doc(doc), index, nil
+}
+
+// flush commits the batches, with expired only those older than the
+// timeout.
+func (c *Client) flush(expired bool) {
+	for _, b := range c.batches {
+		if len(b.documents) == 0 || (expired && !b.expired(c.bulk.Timeout)) {
+			continue
+		}
+		c.commit(b)
+	}
+}
+
+// commit indexes the documents of the batch. Documents rejected with a
+// retryable status, or all of them if the request failed, are sent again
+// with backoff until they are indexed or the client is stopped; they are not
+// acknowledged then, so they are read again after a restart. Documents which
+// cannot be indexed go to the dead letters.
+func (c *Client) commit(b *batch) {
+	documents := b.take()
+	backoff := c.retry.Backoff.Init
+
+	for {
+		retry, err := c.send(b.index, documents)
+		if len(retry) == 0 {
+			return
+		}
+
+		log.Warn("Bulk to %s: retrying %d documents in %v: %v", b.index, len(retry), backoff, err)
+		select {
+		case <-c.done:
+			log.Err("Bulk to %s: stopped with %d documents not indexed: %v", b.index, len(retry), err)
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > c.retry.Backoff.Max {
+			backoff = c.retry.Backoff.Max
+		}
+		documents = retry
+	}
+}
+
+// send issues one bulk request and returns the documents worth retrying.
+func (c *Client) send(index string, documents []*document) ([]*document, error) {
+	service := c.producer.Bulk()
+	byID := make(map[string]*document, len(documents))
+	for _, d := range documents {
+		service.Add(d.request)
+		byID[d.id] = d
+	}
+
+	start := time.Now()
+	response, err := service.Do()
+	c.metrics.Latency.Since(start)
+	if err != nil {
+		c.metrics.Failed.Add(uint64(len(documents)))
+		return documents, err
+	}
+
+	if !response.Errors {
+		log.Debug("client", "elasticSearch bulk of %d documents to %s succeeded", len(documents), index)
+		c.metrics.Acked.Add(uint64(len(documents)))
+		for _, d := range documents {
+			outputs.Done(d.message)
+		}
+		return nil, nil
+	}
+
+	var retry []*document
+	var last error
+	failed := 0
+	for _, item := range response.Failed() {
+		d, ok := byID[item.Id]
+		if !ok {
+			continue
+		}
+
+		last = itemError(item)
+		if retryable(item.Status) {
+			retry = append(retry, d)
+			delete(byID, item.Id)
+			continue
+		}
+
+		failed++
+		c.dead.Message(d.message, last)
+	}
+
+	// documents indexed or written to the dead letters are done
+	for _, d := range documents {
+		if _, ok := byID[d.id]; ok {
+			outputs.Done(d.message)
+		}
+	}
+
+	if failed > 0 {
+		log.Err("Bulk to %s: %d of %d documents failed, last error: %v", index, failed, len(documents), last)
+	}
+
+	c.metrics.Acked.Add(uint64(len(documents) - len(retry) - failed))
+	c.metrics.Failed.Add(uint64(len(retry)))
+	c.metrics.Dropped.Add(uint64(failed))
+	return retry, last
 }
 
 func (c *Client) Stop() {
-	c.producer.Stop()
-}
\ No newline at end of file
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
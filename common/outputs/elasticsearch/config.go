@@ -1,9 +1,28 @@
 package elasticsearch
 
+import (
+	"time"
+
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+	"github.com/queueio/sentry/utils/types/format"
+)
+
 type Config struct {
 	Hosts  []string
+
+	// Index and Pipeline are format strings over the event, like
+	// "logs-%{[service]}-%{+yyyy.MM.dd}". Without Index events go to the
+	// index named by their topic, without Pipeline to no ingest pipeline.
+	Index      *format.String `config:"index"`
+	Pipeline   *format.String `config:"pipeline"`
+	Type       string         `config:"type"`
+	DataStream bool           `config:"data_stream"`
+
 	Size   sizeConfig
 	Bulk   bulkConfig
+	Retry  retryConfig
+	Setup  setupConfig `config:"setup"`
+	DeadLetter deadletter.Config `config:"dead_letter"`
 }
 
 type sizeConfig struct {
@@ -12,9 +31,56 @@ type sizeConfig struct {
 	Retries int
 }
 
+// bulkConfig flushes a bulk request once it holds Size documents or Bytes
+// of documents, or Timeout passed since its first document.
 type bulkConfig struct {
-	Size    int
-	Timeout int
+	Size    int           `validate:"min=1"`
+	Bytes   int           `validate:"min=0"`
+	Timeout time.Duration `validate:"min=0"`
 }
 
-var defaultConfig = Config{}
+// retryConfig retries documents rejected with 429 or 5xx and bulk requests
+// which failed as a whole, until they succeed or the output is closed.
+type retryConfig struct {
+	Backoff backoffConfig
+}
+
+// setupConfig lists the resources the setup command loads, in the order
+// policies, pipelines, templates, so templates can refer to the others.
+type setupConfig struct {
+	Policies  []resourceConfig `config:"policies"`
+	Pipelines []resourceConfig `config:"pipelines"`
+	Templates []resourceConfig `config:"templates"`
+}
+
+// resourceConfig is the body of a resource, read from a JSON file relative
+// to the config path or given inline.
+type resourceConfig struct {
+	Name string                 `config:"name" validate:"required"`
+	File string                 `config:"file"`
+	Body map[string]interface{} `config:"body"`
+}
+
+type backoffConfig struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+var defaultConfig = Config{
+	Type: "doc",
+	Size: sizeConfig{
+		Worker: 1,
+	},
+	Bulk: bulkConfig{
+		Size:    50,
+		Bytes:   5 * 1024 * 1024,
+		Timeout: 1 * time.Second,
+	},
+	Retry: retryConfig{
+		Backoff: backoffConfig{
+			Init: 1 * time.Second,
+			Max:  60 * time.Second,
+		},
+	},
+	DeadLetter: deadletter.DefaultConfig,
+}
@@ -9,20 +9,21 @@ import (
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/config"
 	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
 	"github.com/queueio/sentry/utils/component"
-)
-
-const (
-	docType = "doc"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 type elasticSearch struct {
 	executor *job.Executor
 	channel  *chan *queue.Message
+	dead     *deadletter.DeadLetter
+	metrics  *metric.Output
 }
 
 func init() {
 	outputs.Register("elasticSearch", open)
+	outputs.RegisterSetup("elasticSearch", setup)
 }
 
 func open(info component.Info, config *config.Config) (queue.Handler, error) {
@@ -32,10 +33,18 @@ func open(info component.Info, config *config.Config) (queue.Handler, error) {
 		return outputs.Fail(err)
 	}
 
+	name := outputs.Name(config, "elasticsearch")
+	dead, err := deadletter.New(name, cfg.DeadLetter)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
 	c := make(chan *queue.Message, cfg.Size.Channel)
 	es := &elasticSearch{
 		executor: job.New(),
 		channel:  &c,
+		dead:     dead,
+		metrics:  metric.NewOutput(name),
 	}
 
 	for _, host := range cfg.Hosts {
@@ -53,11 +62,16 @@ func open(info component.Info, config *config.Config) (queue.Handler, error) {
 				id:       uuid.NewV4(),
 				channel:  &c,
 				producer: client,
-				bulk:     &bulk{
-					size:    cfg.Bulk.Size,
-					timeout: int64(cfg.Bulk.Timeout),
-					service: map[string]*service{},
-				},
+				index:    cfg.Index,
+				pipeline: cfg.Pipeline,
+				docType:  cfg.Type,
+				stream:   cfg.DataStream,
+				bulk:     cfg.Bulk,
+				retry:    cfg.Retry,
+				dead:     dead,
+				metrics:  es.metrics,
+				batches:  map[string]*batch{},
+				done:     make(chan struct{}),
 			})
 		}
 	}
@@ -65,13 +79,34 @@ func open(info component.Info, config *config.Config) (queue.Handler, error) {
 	return es, nil
 }
 
-func (e *elasticSearch) Close() error { return nil }
+// Deferred tells publishers that documents are acknowledged once the bulk
+// request indexed them.
+func (e *elasticSearch) Deferred() {}
+
+// Close commits the batched documents of the clients.
+func (e *elasticSearch) Close() error {
+	e.executor.Stop()
+	return e.dead.Close()
+}
 
 func (e *elasticSearch) HandleMessage(message *queue.Message) error {
-	*e.channel <- message
-	log.Debug("elasticSearch", "message to channel ok")
+	messages, err := outputs.Unpack(message)
+	if err != nil {
+		e.metrics.Dropped.Inc()
+		e.dead.Message(message, err)
+		outputs.Done(message)
+		return nil
+	}
+
+	for _, m := range messages {
+		e.metrics.Published.Inc()
+		*e.channel <- m
+	}
 	return nil
 }
 
 func (e *elasticSearch) LogFailedMessage(message *queue.Message) {
+	e.metrics.Dropped.Inc()
+	e.dead.Message(message, outputs.ErrAttempts)
+	outputs.Done(message)
 }
\ No newline at end of file
@@ -0,0 +1,166 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/paths"
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/component"
+)
+
+type resourceKind struct {
+	name string
+	path string
+}
+
+var (
+	policyKind   = resourceKind{name: "ILM policy", path: "/_ilm/policy/"}
+	pipelineKind = resourceKind{name: "ingest pipeline", path: "/_ingest/pipeline/"}
+	templateKind = resourceKind{name: "index template", path: "/_index_template/"}
+)
+
+type setupClient struct {
+	http    *http.Client
+	host     string
+	options  outputs.SetupOptions
+}
+
+// setup loads the configured ILM policies, ingest pipelines and index
+// templates into the first host. Resources which exist already are kept
+// unless overwriting.
+func setup(info component.Info, config *config.Config, options outputs.SetupOptions) error {
+	cfg := defaultConfig
+	err := config.Unpack(&cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Hosts) == 0 {
+		return fmt.Errorf("elasticsearch setup requires hosts")
+	}
+
+	c := &setupClient{
+		http:    &http.Client{Timeout: 30 * time.Second},
+		host:    strings.TrimRight(cfg.Hosts[0], "/"),
+		options: options,
+	}
+
+	kinds := []struct {
+		kind      resourceKind
+		resources []resourceConfig
+	}{
+		{policyKind, cfg.Setup.Policies},
+		{pipelineKind, cfg.Setup.Pipelines},
+		{templateKind, cfg.Setup.Templates},
+	}
+	for _, k := range kinds {
+		for _, resource := range k.resources {
+			if err := c.load(k.kind, resource); err != nil {
+				return fmt.Errorf("loading %s %s failed: %v", k.kind.name, resource.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *setupClient) load(kind resourceKind, resource resourceConfig) error {
+	body, err := resource.body()
+	if err != nil {
+		return err
+	}
+
+	path := kind.path + url.PathEscape(resource.Name)
+	exists, err := c.exists(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case exists && !c.options.Overwrite:
+		c.report("Keeping existing %s %s", kind.name, resource.Name)
+		return nil
+	case c.options.DryRun:
+		c.report("Would load %s %s:\n%s", kind.name, resource.Name, body)
+		return nil
+	}
+
+	if _, err := c.request("PUT", path, body); err != nil {
+		return err
+	}
+	c.report("Loaded %s %s", kind.name, resource.Name)
+	return nil
+}
+
+// report tells what the setup does, it is the result of the setup command.
+func (c *setupClient) report(format string, v ...interface{}) {
+	if c.options.Writer == nil {
+		log.Info(format, v...)
+		return
+	}
+	fmt.Fprintf(c.options.Writer, format+"\n", v...)
+}
+
+func (c *setupClient) exists(path string) (bool, error) {
+	status, err := c.request("GET", path, nil)
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *setupClient) request(method, path string, body []byte) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequest(method, c.host+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if response.StatusCode/100 != 2 {
+		return response.StatusCode, fmt.Errorf("%s %s returned %d: %s",
+			method, path, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return response.StatusCode, nil
+}
+
+func (r resourceConfig) body() ([]byte, error) {
+	if r.File == "" {
+		if r.Body == nil {
+			return nil, fmt.Errorf("file or body required")
+		}
+		return json.MarshalIndent(r.Body, "", "  ")
+	}
+
+	body, err := ioutil.ReadFile(paths.Resolve(paths.Config, r.File))
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%s is no valid JSON", r.File)
+	}
+	return body, nil
+}
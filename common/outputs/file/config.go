@@ -0,0 +1,33 @@
+package file
+
+import (
+	"time"
+
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+)
+
+// Config writes the events to <path>/<filename>, rotated after Limit bytes
+// or at every Interval boundary, keeping KeepFiles files. The filename
+// defaults to the name of the output.
+type Config struct {
+	Path        string        `config:"path"`
+	Filename    string        `config:"filename"`
+	Limit       uint64        `config:"limit" validate:"min=1"`
+	KeepFiles   int           `config:"keepfiles" validate:"min=2"`
+	Permissions uint32        `config:"permissions"`
+	Interval    time.Duration `config:"interval" validate:"min=0"`
+	Compress    bool          `config:"compress"`
+	Codec       codec.Config  `config:"codec"`
+
+	DeadLetter deadletter.Config `config:"dead_letter"`
+}
+
+var defaultConfig = Config{
+	Path:        "file",
+	Limit:       10 * 1024 * 1024,
+	KeepFiles:   7,
+	Permissions: 0600,
+	Codec:       codec.DefaultConfig,
+	DeadLetter:  deadletter.DefaultConfig,
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/paths"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+	"github.com/queueio/sentry/utils/component"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+var fileDebug = log.MakeDebug("file")
+
+type file struct {
+	sync.Mutex
+	rotate *log.Rotate
+	codec  codec.Codec
+	dead   *deadletter.DeadLetter
+	metrics *metric.Output
+}
+
+func init() {
+	outputs.Register("file", open)
+}
+
+func open(info component.Info, config *config.Config) (queue.Handler, error) {
+	cfg := defaultConfig
+	err := config.Unpack(&cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	// outputs of different names do not share their files
+	name := outputs.Name(config, "file")
+	if cfg.Filename == "" {
+		cfg.Filename = name
+	}
+
+	rotate := &log.Rotate{
+		Path:        paths.Resolve(paths.Data, cfg.Path),
+		Name:        cfg.Filename,
+		Limit:       &cfg.Limit,
+		KeepFiles:   &cfg.KeepFiles,
+		Permissions: &cfg.Permissions,
+		Interval:    cfg.Interval,
+		Compress:    cfg.Compress,
+	}
+
+	if err := rotate.CreateDirectory(); err != nil {
+		return outputs.Fail(err)
+	}
+	if err := rotate.CheckIfConfigSane(); err != nil {
+		return outputs.Fail(err)
+	}
+
+	encoder, err := codec.New(cfg.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	dead, err := deadletter.New(name, cfg.DeadLetter)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	fileDebug("Writing events to %s", rotate.FilePath(0))
+	return &file{
+		rotate:  rotate,
+		codec:   encoder,
+		dead:    dead,
+		metrics: metric.NewOutput(name),
+	}, nil
+}
+
+func (f *file) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.rotate.Close(); err != nil {
+		return err
+	}
+	return f.dead.Close()
+}
+
+// HandleMessage writes the events in the file codec, one per line. The lines
+// of a message are written at once, so a failed write does not repeat the
+// lines before it when the queue delivers the message again. A short write
+// may still leave a partial line in the file.
+func (f *file) HandleMessage(message *queue.Message) error {
+	messages, err := outputs.Unpack(message)
+	if err != nil {
+		f.metrics.Dropped.Inc()
+		f.dead.Message(message, err)
+		return nil
+	}
+
+	var (
+		buffer  bytes.Buffer
+		lines   int
+		dropped []*queue.Message
+		errs    []error
+	)
+	for _, m := range messages {
+		f.metrics.Published.Inc()
+		event, err := codec.Decode(m.Body)
+		if err == nil {
+			var line []byte
+			if line, err = f.codec.Encode(event); err == nil {
+				if lines > 0 {
+					buffer.WriteByte('\n')
+				}
+				buffer.Write(line)
+				lines++
+				continue
+			}
+		}
+		dropped = append(dropped, m)
+		errs = append(errs, err)
+	}
+
+	if lines > 0 {
+		start := time.Now()
+		err := f.write(buffer.Bytes())
+		f.metrics.Latency.Since(start)
+		if err != nil {
+			f.metrics.Failed.Add(uint64(lines))
+			return err
+		}
+		f.metrics.Acked.Add(uint64(lines))
+	}
+
+	// dead letters only once the message is written, it is not delivered again
+	for i, m := range dropped {
+		f.metrics.Dropped.Inc()
+		f.dead.Message(m, errs[i])
+	}
+	return nil
+}
+
+func (f *file) write(line []byte) error {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.rotate.WriteLine(line)
+}
+
+func (f *file) LogFailedMessage(message *queue.Message) {
+	f.metrics.Dropped.Inc()
+	f.dead.Message(message, outputs.ErrAttempts)
+}
@@ -1 +1,235 @@
 package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+type Client struct {
+	id      uuid.UUID
+	channel *chan *queue.Message
+	config  *Config
+	encoder *encoder
+	http    *http.Client
+	urls    []string
+	url     int
+	dead    *deadletter.DeadLetter
+	metrics *metric.Output
+
+	ticker   *time.Ticker
+	buffer   []byte
+	messages []*queue.Message
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (c *Client) ID() uuid.UUID {
+	return c.id
+}
+
+// Run batches the messages into line protocol and writes the batch once it
+// holds Batch.Size points or Batch.Interval passed.
+func (c *Client) Run() error {
+	defer c.ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			c.flush()
+			return nil
+		case msg, open := <-*c.channel:
+			if !open {
+				c.flush()
+				return outputs.ErrClosed
+			}
+
+			c.add(msg)
+			if len(c.messages) >= c.config.Batch.Size {
+				c.flush()
+			}
+		case <-c.ticker.C:
+			c.flush()
+		}
+	}
+}
+
+func (c *Client) add(msg *queue.Message) {
+	event, err := codec.Decode(msg.Body)
+	if err != nil {
+		log.Err("decoding event failed: %s", err.Error())
+		c.metrics.Dropped.Inc()
+		c.dead.Message(msg, err)
+		outputs.Done(msg)
+		return
+	}
+
+	buffer, err := c.encoder.encode(c.buffer, event)
+	if err != nil {
+		c.metrics.Dropped.Inc()
+		c.dead.Message(msg, err)
+		outputs.Done(msg)
+		return
+	}
+
+	c.buffer = buffer
+	c.messages = append(c.messages, msg)
+}
+
+// flush writes the batch. Transport errors, 429 and 5xx answers are retried
+// with backoff on the next URL until the write succeeds or the client is
+// stopped, points not written then are not acknowledged. Other client errors
+// are not retried: points InfluxDB rejected as invalid go to the dead
+// letters, with a partial write the others were stored, otherwise they are
+// sent again without the rejected ones. Batches whose rejected points are not
+// known go to the dead letters as a whole.
+func (c *Client) flush() {
+	if len(c.messages) == 0 {
+		return
+	}
+
+	messages, body := c.messages, c.buffer
+	backoff := c.config.Retry.Backoff.Init
+	for {
+		start := time.Now()
+		err := c.post(c.urls[c.url], body)
+		c.metrics.Latency.Since(start)
+		if err == nil {
+			c.metrics.Acked.Add(uint64(len(messages)))
+			outputs.Done(messages...)
+			break
+		}
+
+		if e, ok := err.(*WriteError); ok && !e.Temporary() {
+			if rejected, ok := e.Rejected(body); ok && e.Status == http.StatusBadRequest {
+				log.Err("InfluxDB rejected %d points of a batch of %d: %s", len(rejected), len(messages), e.Message)
+				messages, body = c.reject(messages, body, rejected, err)
+				if !e.Partial() && len(messages) > 0 {
+					continue
+				}
+
+				c.metrics.Acked.Add(uint64(len(messages)))
+				outputs.Done(messages...)
+				break
+			}
+
+			// The rejected points cannot be told apart from the others, or
+			// the request fails whatever points it holds: the batch goes to
+			// the dead letters as a whole.
+			log.Err("InfluxDB rejected a batch of %d points: %v", len(messages), err)
+			c.reject(messages, body, nil, err)
+			break
+		}
+
+		c.metrics.Failed.Add(uint64(len(messages)))
+		c.url = (c.url + 1) % len(c.urls)
+		log.Warn("Writing %d points to InfluxDB failed: %v. Retrying in %v", len(messages), err, backoff)
+		select {
+		case <-c.done:
+			log.Err("Stopped with %d points not written to InfluxDB: %v", len(messages), err)
+			c.reset()
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > c.config.Retry.Backoff.Max {
+			backoff = c.config.Retry.Backoff.Max
+		}
+	}
+
+	c.reset()
+}
+
+// reject writes the rejected points to the dead letters, all of them if
+// rejected is nil, and returns the others along with their lines.
+func (c *Client) reject(messages []*queue.Message, body []byte, rejected map[int]bool, err error) ([]*queue.Message, []byte) {
+	var kept []*queue.Message
+	var lines []byte
+	for i, line := range bytes.SplitAfter(body, []byte{'\n'}) {
+		if i >= len(messages) {
+			break
+		}
+		if rejected != nil && !rejected[i] {
+			kept = append(kept, messages[i])
+			lines = append(lines, line...)
+			continue
+		}
+
+		c.metrics.Dropped.Inc()
+		c.dead.Message(messages[i], err)
+		outputs.Done(messages[i])
+	}
+	return kept, lines
+}
+
+func (c *Client) reset() {
+	c.buffer = c.buffer[:0]
+	c.messages = c.messages[:0]
+}
+
+func (c *Client) post(host string, body []byte) error {
+	params := url.Values{}
+	params.Set("db", c.config.Database)
+	params.Set("precision", "ns")
+	if c.config.Policy != "" {
+		params.Set("rp", c.config.Policy)
+	}
+
+	request, err := http.NewRequest("POST", strings.TrimRight(host, "/")+"/write?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if c.config.Username != "" {
+		request.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, response.Body)
+		return nil
+	}
+
+	return readError(response)
+}
+
+func readError(response *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 64*1024))
+
+	var v struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &v); err == nil && v.Error != "" {
+		message = v.Error
+	}
+
+	return &WriteError{Status: response.StatusCode, Message: message}
+}
+
+func (c *Client) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
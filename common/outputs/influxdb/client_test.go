@@ -0,0 +1,78 @@
+// +build !integration
+
+package influxdb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+// flushStatus flushes a batch of two points to a server answering every
+// write with the status and body, and returns the number of writes. A batch
+// still retried after a second is given up by stopping the client.
+func flushStatus(name string, status int, body string) (int32, *metric.Output) {
+	var writes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&writes, 1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := defaultConfig
+	config.URLs = []string{server.URL}
+	config.Retry.Backoff.Init = 10 * time.Millisecond
+	config.Retry.Backoff.Max = 10 * time.Millisecond
+
+	c := &Client{
+		config:   &config,
+		http:     server.Client(),
+		urls:     config.URLs,
+		metrics:  metric.NewOutput(name),
+		buffer:   []byte("cpu value=1 1\ncpu value=2 2\n"),
+		messages: []*queue.Message{{Body: []byte("{}")}, {Body: []byte("{}")}},
+		done:     make(chan struct{}),
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		c.flush()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		c.Stop()
+		<-flushed
+	}
+	return atomic.LoadInt32(&writes), c.metrics
+}
+
+func TestFlushBadRequest(t *testing.T) {
+	writes, metrics := flushStatus("test_bad_request", http.StatusBadRequest, `{"error":"database is read only"}`)
+	assert.Equal(t, int32(1), writes)
+	assert.Equal(t, uint64(2), metrics.Dropped.Get())
+	assert.Equal(t, uint64(0), metrics.Acked.Get())
+}
+
+func TestFlushEntityTooLarge(t *testing.T) {
+	writes, metrics := flushStatus("test_too_large", http.StatusRequestEntityTooLarge, `{"error":"request entity too large"}`)
+	assert.Equal(t, int32(1), writes)
+	assert.Equal(t, uint64(2), metrics.Dropped.Get())
+	assert.Equal(t, uint64(0), metrics.Acked.Get())
+}
+
+func TestFlushServerErrorRetried(t *testing.T) {
+	writes, metrics := flushStatus("test_server_error", http.StatusServiceUnavailable, "")
+	assert.True(t, writes > 1)
+	assert.Equal(t, uint64(0), metrics.Dropped.Get())
+}
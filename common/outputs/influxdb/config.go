@@ -1 +1,80 @@
 package influxdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+)
+
+type Config struct {
+	URLs     []string `config:"urls" validate:"required"`
+	Database string   `config:"database" validate:"required"`
+	Policy   string   `config:"retention_policy"`
+	Username string   `config:"username"`
+	Password string   `config:"password"`
+	Timeout  time.Duration
+
+	// Measurement names the points, unless the event has a value for the
+	// measurement field. Without both the event topic is used.
+	Measurement      string   `config:"measurement"`
+	MeasurementField string   `config:"measurement_field"`
+	Tags             []string `config:"tags"`
+	Fields           []string `config:"fields"`
+
+	Size       sizeConfig
+	Batch      batchConfig
+	Retry      retryConfig
+	DeadLetter deadletter.Config `config:"dead_letter"`
+}
+
+type sizeConfig struct {
+	Worker  int `validate:"min=1"`
+	Channel int `validate:"min=0"`
+}
+
+type batchConfig struct {
+	Size     int `validate:"min=1"`
+	Interval time.Duration
+}
+
+// retryConfig retries failed writes until they succeed or the output is
+// closed.
+type retryConfig struct {
+	Backoff backoffConfig
+}
+
+type backoffConfig struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+var defaultConfig = Config{
+	Timeout: 30 * time.Second,
+	Size: sizeConfig{
+		Worker: 1,
+	},
+	Batch: batchConfig{
+		Size:     1000,
+		Interval: 1 * time.Second,
+	},
+	Retry: retryConfig{
+		Backoff: backoffConfig{
+			Init: 1 * time.Second,
+			Max:  60 * time.Second,
+		},
+	},
+	DeadLetter: deadletter.DefaultConfig,
+}
+
+func (c *Config) Validate() error {
+	if c.Batch.Interval <= 0 {
+		return fmt.Errorf("InfluxDB batch interval must be greater than 0")
+	}
+
+	if c.Retry.Backoff.Init <= 0 || c.Retry.Backoff.Max < c.Retry.Backoff.Init {
+		return fmt.Errorf("InfluxDB retry backoff must be greater than 0 and max not less than init")
+	}
+
+	return nil
+}
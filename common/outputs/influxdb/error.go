@@ -1 +1,67 @@
 package influxdb
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// InfluxDB 2 numbers the lines it failed to parse
+	rejectedLine = regexp.MustCompile(`line (\d+):`)
+	// InfluxDB 1 quotes them
+	unparsableLine = regexp.MustCompile(`unable to parse '(.*?)': `)
+)
+
+// WriteError is a write InfluxDB answered with an error status.
+type WriteError struct {
+	Status  int
+	Message string
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("InfluxDB write failed with status %d: %s", e.Status, e.Message)
+}
+
+// Temporary reports whether the write may succeed when it is sent again,
+// which is the case for throttled writes and server errors.
+func (e *WriteError) Temporary() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// Partial reports whether InfluxDB stored the valid points of the batch and
+// only rejected some of them.
+func (e *WriteError) Partial() bool {
+	return strings.HasPrefix(e.Message, "partial write")
+}
+
+// Rejected returns the indexes of the lines of the batch InfluxDB rejected,
+// ok is false if the error does not tell which lines they are.
+func (e *WriteError) Rejected(batch []byte) (map[int]bool, bool) {
+	lines := bytes.Split(bytes.TrimSuffix(batch, []byte{'\n'}), []byte{'\n'})
+	rejected := map[int]bool{}
+	for _, m := range rejectedLine.FindAllStringSubmatch(e.Message, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(lines) {
+			return nil, false
+		}
+		rejected[n-1] = true
+	}
+
+	for _, m := range unparsableLine.FindAllStringSubmatch(e.Message, -1) {
+		found := false
+		for i, line := range lines {
+			if !rejected[i] && string(line) == m[1] {
+				rejected[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return rejected, len(rejected) > 0
+}
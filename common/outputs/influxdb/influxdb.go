@@ -1 +1,102 @@
 package influxdb
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/queueio/sentry/utils/component"
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/job"
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/deadletter"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+type influxDB struct {
+	executor *job.Executor
+	channel  *chan *queue.Message
+	dead     *deadletter.DeadLetter
+	metrics  *metric.Output
+}
+
+func init() {
+	outputs.Register("influxdb", open)
+}
+
+func open(info component.Info, config *config.Config) (queue.Handler, error) {
+	cfg := defaultConfig
+	err := config.Unpack(&cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	name := outputs.Name(config, "influxdb")
+	dead, err := deadletter.New(name, cfg.DeadLetter)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	c := make(chan *queue.Message, cfg.Size.Channel)
+	i := &influxDB{
+		executor: job.New(),
+		channel:  &c,
+		dead:     dead,
+		metrics:  metric.NewOutput(name),
+	}
+
+	encoder := newEncoder(&cfg)
+	for n := 0; n < cfg.Size.Worker; n++ {
+		i.executor.Start(&Client{
+			id:      uuid.NewV4(),
+			channel: &c,
+			config:  &cfg,
+			encoder: encoder,
+			http:    &http.Client{Timeout: cfg.Timeout},
+			urls:    cfg.URLs,
+			url:     n % len(cfg.URLs),
+			dead:    dead,
+			metrics: i.metrics,
+			ticker:  time.NewTicker(cfg.Batch.Interval),
+			done:    make(chan struct{}),
+		})
+	}
+
+	log.Debug("influxdb", "%d clients writing to %v", cfg.Size.Worker, cfg.URLs)
+	return i, nil
+}
+
+// Deferred tells publishers that points are acknowledged once the batch
+// was written.
+func (i *influxDB) Deferred() {}
+
+// Close flushes the batches of the clients.
+func (i *influxDB) Close() error {
+	i.executor.Stop()
+	return i.dead.Close()
+}
+
+func (i *influxDB) HandleMessage(message *queue.Message) error {
+	messages, err := outputs.Unpack(message)
+	if err != nil {
+		i.metrics.Dropped.Inc()
+		i.dead.Message(message, err)
+		outputs.Done(message)
+		return nil
+	}
+
+	for _, m := range messages {
+		i.metrics.Published.Inc()
+		*i.channel <- m
+	}
+	return nil
+}
+
+func (i *influxDB) LogFailedMessage(message *queue.Message) {
+	i.metrics.Dropped.Inc()
+	i.dead.Message(message, outputs.ErrAttempts)
+	outputs.Done(message)
+}
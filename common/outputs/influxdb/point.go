@@ -0,0 +1,183 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+var (
+	ErrNoMeasurement = errors.New("event has no measurement")
+	ErrNoFields      = errors.New("event has no fields")
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// encoder turns events into InfluxDB line protocol.
+type encoder struct {
+	measurement string
+	field       string
+	tags        map[string]struct{}
+	fields      map[string]struct{}
+}
+
+func newEncoder(config *Config) *encoder {
+	return &encoder{
+		measurement: config.Measurement,
+		field:       config.MeasurementField,
+		tags:        set(config.Tags),
+		fields:      set(config.Fields),
+	}
+}
+
+func set(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	s := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		s[key] = struct{}{}
+	}
+	return s
+}
+
+// encode appends the point of the event to buf. Nested objects are
+// flattened into dotted keys. Tag keys select tags, field keys select
+// fields; without field keys every value which is not a tag is a field.
+func (e *encoder) encode(buf []byte, evt *event.Event) ([]byte, error) {
+	values := evt.Fields.Flatten()
+
+	measurement := e.measurement
+	if v, ok := values[e.field]; ok && e.field != "" {
+		measurement = fmt.Sprint(v)
+		delete(values, e.field)
+	}
+	if measurement == "" {
+		measurement = evt.Topic
+	}
+	if measurement == "" {
+		return buf, ErrNoMeasurement
+	}
+
+	var tags, fields []string
+	for key, value := range values {
+		if _, ok := e.tags[key]; ok {
+			if s := fmt.Sprint(value); s != "" {
+				tags = append(tags, key)
+			}
+			continue
+		}
+		if e.fields != nil {
+			if _, ok := e.fields[key]; !ok {
+				continue
+			}
+		}
+		if _, ok := fieldValue(value); ok {
+			fields = append(fields, key)
+		}
+	}
+	if len(fields) == 0 {
+		return buf, ErrNoFields
+	}
+
+	// sorted tags are what InfluxDB prefers, sorted fields keep lines stable
+	sort.Strings(tags)
+	sort.Strings(fields)
+
+	buf = append(buf, measurementEscaper.Replace(measurement)...)
+	for _, key := range tags {
+		buf = append(buf, ',')
+		buf = append(buf, keyEscaper.Replace(key)...)
+		buf = append(buf, '=')
+		buf = append(buf, keyEscaper.Replace(fmt.Sprint(values[key]))...)
+	}
+
+	for i, key := range fields {
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		value, _ := fieldValue(values[key])
+		buf = append(buf, keyEscaper.Replace(key)...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+	}
+
+	if !evt.Timestamp.IsZero() {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, evt.Timestamp.UnixNano(), 10)
+	}
+
+	return append(buf, '\n'), nil
+}
+
+// fieldValue formats the value as line protocol field value, ok is false for
+// values a field cannot hold. NaN and infinite floats are left out, InfluxDB
+// rejects them.
+func fieldValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String() + "i", true
+		}
+		if f, err := v.Float64(); err == nil && isFinite(f) {
+			return v.String(), true
+		}
+		return "", false
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), isFinite(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), isFinite(float64(v))
+	case int:
+		return strconv.FormatInt(int64(v), 10) + "i", true
+	case int8:
+		return strconv.FormatInt(int64(v), 10) + "i", true
+	case int16:
+		return strconv.FormatInt(int64(v), 10) + "i", true
+	case int32:
+		return strconv.FormatInt(int64(v), 10) + "i", true
+	case int64:
+		return strconv.FormatInt(v, 10) + "i", true
+	case uint:
+		return unsignedValue(uint64(v)), true
+	case uint8:
+		return unsignedValue(uint64(v)), true
+	case uint16:
+		return unsignedValue(uint64(v)), true
+	case uint32:
+		return unsignedValue(uint64(v)), true
+	case uint64:
+		return unsignedValue(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case string:
+		return `"` + stringEscaper.Replace(v) + `"`, true
+	default:
+		return "", false
+	}
+}
+
+// unsignedValue formats values which fit an integer field as such, larger
+// ones as unsigned field.
+func unsignedValue(v uint64) string {
+	if v <= math.MaxInt64 {
+		return strconv.FormatUint(v, 10) + "i"
+	}
+	return strconv.FormatUint(v, 10) + "u"
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
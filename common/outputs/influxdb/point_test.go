@@ -0,0 +1,122 @@
+// +build !integration
+
+package influxdb
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+func TestEncode(t *testing.T) {
+	ts := time.Unix(1, 5)
+	tests := []struct {
+		name   string
+		config Config
+		event  event.Event
+		line   string
+		err    error
+	}{
+		{
+			name:   "tags and fields",
+			config: Config{Tags: []string{"host", "empty"}},
+			event: event.Event{
+				Topic:     "cpu",
+				Timestamp: ts,
+				Fields:    maps.StringIf{"host": "a", "empty": "", "load": 0.5, "cores": 4},
+			},
+			line: "cpu,host=a cores=4i,load=0.5 1000000005\n",
+		},
+		{
+			name:   "selected fields",
+			config: Config{Fields: []string{"load"}},
+			event: event.Event{
+				Topic:  "cpu",
+				Fields: maps.StringIf{"load": 0.5, "cores": 4},
+			},
+			line: "cpu load=0.5\n",
+		},
+		{
+			name:   "nested fields",
+			config: Config{Tags: []string{"host.name"}},
+			event: event.Event{
+				Topic:  "cpu",
+				Fields: maps.StringIf{"host": maps.StringIf{"name": "a"}, "load": maps.StringIf{"1m": 1.5}},
+			},
+			line: "cpu,host.name=a load.1m=1.5\n",
+		},
+		{
+			name:   "escaping",
+			config: Config{Measurement: "cpu load,total", Tags: []string{"host name"}},
+			event: event.Event{
+				Fields: maps.StringIf{"host name": "a,b=c", "a=b,c": `say "hi" \ bye`},
+			},
+			line: `cpu\ load\,total,host\ name=a\,b\=c a\=b\,c="say \"hi\" \\ bye"` + "\n",
+		},
+		{
+			name:   "measurement field",
+			config: Config{Measurement: "default", MeasurementField: "kind"},
+			event: event.Event{
+				Topic:  "topic",
+				Fields: maps.StringIf{"kind": "mem", "used": true},
+			},
+			line: "mem used=true\n",
+		},
+		{
+			name:   "integer suffixes",
+			config: Config{},
+			event: event.Event{
+				Topic: "n",
+				Fields: maps.StringIf{
+					"a": int8(-1), "b": uint32(2), "c": uint64(math.MaxInt64),
+					"d": uint64(math.MaxUint64), "e": int64(-3),
+				},
+			},
+			line: "n a=-1i,b=2i,c=9223372036854775807i,d=18446744073709551615u,e=-3i\n",
+		},
+		{
+			name:   "json numbers",
+			config: Config{},
+			event: event.Event{
+				Topic:  "n",
+				Fields: maps.StringIf{"a": json.Number("12"), "b": json.Number("1.25"), "c": json.Number("1e400")},
+			},
+			line: "n a=12i,b=1.25\n",
+		},
+		{
+			name:   "not finite floats",
+			config: Config{},
+			event: event.Event{
+				Topic:  "n",
+				Fields: maps.StringIf{"a": math.NaN(), "b": math.Inf(1), "c": float32(math.Inf(-1)), "d": 2.0},
+			},
+			line: "n d=2\n",
+		},
+		{
+			name:   "no fields left",
+			config: Config{},
+			event:  event.Event{Topic: "n", Fields: maps.StringIf{"a": math.NaN()}},
+			err:    ErrNoFields,
+		},
+		{
+			name:   "no measurement",
+			config: Config{},
+			event:  event.Event{Fields: maps.StringIf{"a": 1}},
+			err:    ErrNoMeasurement,
+		},
+	}
+
+	for _, test := range tests {
+		line, err := newEncoder(&test.config).encode(nil, &test.event)
+		assert.Equal(t, test.err, err, test.name)
+		if test.err == nil {
+			assert.Equal(t, test.line, string(line), test.name)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package node
+
+import (
+	"time"
+
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+// batch collects the encoded events of one topic until they are published.
+type batch struct {
+	events    []event.Event
+	bodies    [][]byte
+	bytes     int
+	timestamp time.Time // first event added
+}
+
+func (b *batch) add(e event.Event, body []byte) {
+	if len(b.events) == 0 {
+		b.timestamp = time.Now()
+	}
+	b.events = append(b.events, e)
+	b.bodies = append(b.bodies, body)
+	b.bytes += len(body)
+}
+
+func (b *batch) full(config batchConfig) bool {
+	return len(b.events) >= config.Size || (config.Bytes > 0 && b.bytes >= config.Bytes)
+}
+
+func (b *batch) expired(linger time.Duration) bool {
+	return len(b.events) > 0 && time.Since(b.timestamp) >= linger
+}
+
+func (b *batch) take() ([]event.Event, [][]byte) {
+	events, bodies := b.events, b.bodies
+	b.events, b.bodies, b.bytes = nil, nil, 0
+	return events, bodies
+}
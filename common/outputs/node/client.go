@@ -1,15 +1,17 @@
 package node
 
 import (
+	"sync"
 	"time"
-	"encoding/json"
 
 	"github.com/satori/go.uuid"
 
 	"github.com/queueio/sentry/utils/log"
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
 	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 type Client struct {
@@ -17,8 +19,28 @@ type Client struct {
 
     timer     *time.Ticker
     channel   *chan event.Event
+    retry      retryConfig
+    counters  *counters
+    dropped    uint64
+    codec      codec.Codec
 
+    batch       batchConfig
+    compression string
+    batches     map[string]*batch
+
+    group       int
+    discovery  *discovery
 	producer  *queue.Producer
+	address    string
+	version    uint64
+	failovers  int
+	metrics   *metric.Output
+	queued    *metric.Gauge
+
+	shutdown   time.Duration
+	cancel     <-chan struct{} // ends the retries of a publish
+	done       chan struct{}
+	stopOnce   sync.Once
 }
 
 func (c *Client) ID() uuid.UUID {
@@ -26,28 +48,32 @@ func (c *Client) ID() uuid.UUID {
 }
 
 func (c *Client) Run() error {
+	linger := time.NewTicker(c.tick())
+	defer linger.Stop()
+	defer c.disconnect()
+
+	if err := c.connect(); err != nil {
+		log.Err("Connecting group %d failed: %v", c.group, err)
+	}
+
+	c.cancel = c.done
     for {
         select {
+        case <-c.done:
+        	c.drain()
+        	return nil
         case event, open := <-*c.channel:
         	if !open {
         		c.Stop()
                 return outputs.ErrClosed
             }
 
-            body, err := json.Marshal(event)
-            if err != nil {
-            	log.Err(err.Error())
-            	continue
-			}
-
-            err = c.producer.Publish(event.Topic, body)
-            if err != nil {
-            	log.Err(err.Error())
-			}
+            c.add(event)
+        case <-linger.C:
+        	c.flush()
         case <-c.timer.C:
-        	if err := c.producer.Ping(); err != nil {
-        		log.Warn(err.Error())
-			}
+        	c.check()
+			c.report()
         	log.Warn(outputs.ErrEmpty.Error())
         }
     }
@@ -55,6 +81,204 @@ func (c *Client) Run() error {
 	return nil
 }
 
+// drain publishes the events left in the channel and the batches of a
+// stopped client, for at most the shutdown timeout. Events not published by
+// then stay unacknowledged.
+func (c *Client) drain() {
+	deadline := make(chan struct{})
+	timer := time.AfterFunc(c.shutdown, func() { close(deadline) })
+	defer timer.Stop()
+	c.cancel = deadline
+
+	for empty := false; !empty; {
+		select {
+		case event, open := <-*c.channel:
+			if !open {
+				empty = true
+				break
+			}
+			c.add(event)
+		default:
+			empty = true
+		}
+	}
+
+	for topic, b := range c.batches {
+		select {
+		case <-deadline:
+			log.Warn("Group %d stopped with unpublished events", c.group)
+			return
+		default:
+		}
+		if len(b.events) > 0 {
+			c.publish(topic, b)
+		}
+	}
+}
+
+// tick checks for expired batches often enough to publish them at most a
+// tenth of the linger time late.
+func (c *Client) tick() time.Duration {
+	if tick := c.batch.Linger / 10; tick >= 10*time.Millisecond {
+		return tick
+	}
+	return 10 * time.Millisecond
+}
+
+func (c *Client) add(event event.Event) {
+	body, err := c.codec.Encode(&event)
+	if err != nil {
+		log.Err("Dropping event which cannot be encoded: %s", err.Error())
+		c.metrics.Dropped.Inc()
+		outputs.ACK(event)
+		return
+	}
+
+	b, ok := c.batches[event.Topic]
+	if !ok {
+		b = &batch{}
+		c.batches[event.Topic] = b
+	}
+
+	b.add(event, body)
+	if b.full(c.batch) {
+		c.publish(event.Topic, b)
+	}
+}
+
+// connect creates the producer the group publishes to, the next one in the
+// list for every failover. A connected producer which is still the pick is
+// kept.
+func (c *Client) connect() error {
+	address, version, ok := c.discovery.pick(c.group, c.failovers)
+	if !ok {
+		return ErrNoProducer
+	}
+
+	c.version = version
+	if c.producer != nil && address == c.address {
+		return nil
+	}
+
+	c.disconnect()
+	producer, err := queue.NewProducer(address, queue.NewConfig())
+	if err != nil {
+		return err
+	}
+
+	log.Info("Group %d publishing to %s", c.group, address)
+	c.producer, c.address = producer, address
+	return nil
+}
+
+func (c *Client) disconnect() {
+	if c.producer != nil {
+		c.producer.Stop()
+		c.producer = nil
+	}
+}
+
+// failover drops the producer, so the next connect picks another one.
+func (c *Client) failover(err error) {
+	log.Warn("Producer %s of group %d failed: %v. Failing over", c.address, c.group, err)
+	c.failovers++
+	c.disconnect()
+}
+
+// check pings the producer and moves the group when the producer list
+// changed, starting over with the first pick.
+func (c *Client) check() {
+	if c.discovery.changed(c.version) {
+		c.failovers = 0
+	}
+
+	if err := c.connect(); err != nil {
+		log.Err("Connecting group %d failed: %v", c.group, err)
+		return
+	}
+
+	if err := c.producer.Ping(); err != nil {
+		c.failover(err)
+	}
+}
+
+// flush publishes the batches which lingered long enough.
+func (c *Client) flush() {
+	for topic, b := range c.batches {
+		if b.expired(c.batch.Linger) {
+			c.publish(topic, b)
+		}
+	}
+}
+
+// publish sends the batch to the queue and acknowledges its events. With
+// compression the batch goes as one packed message, otherwise as one
+// message per event. Failed publishes fail over to the next producer and
+// are retried with backoff until they succeed or the client is stopped, so
+// an unreachable queue blocks the collectors instead of losing events.
+func (c *Client) publish(topic string, b *batch) {
+	events, bodies := b.take()
+
+	send := func() error {
+		if len(bodies) == 1 {
+			return c.producer.Publish(topic, bodies[0])
+		}
+		return c.producer.MultiPublish(topic, bodies)
+	}
+
+	if c.compression != codec.CompressionNone {
+		body, err := codec.Pack(bodies, c.compression)
+		if err != nil {
+			log.Err("Dropping %d events which cannot be packed: %s", len(events), err.Error())
+			c.metrics.Dropped.Add(uint64(len(events)))
+			outputs.ACK(events...)
+			return
+		}
+		send = func() error { return c.producer.Publish(topic, body) }
+	}
+
+	backoff := c.retry.Min
+	for {
+		err := c.connect()
+		if err == nil {
+			start := time.Now()
+			err = send()
+			c.metrics.Latency.Since(start)
+			if err == nil {
+				c.metrics.Acked.Add(uint64(len(events)))
+				outputs.ACK(events...)
+				return
+			}
+			c.failover(err)
+		}
+
+		c.metrics.Failed.Add(uint64(len(events)))
+
+		log.Err("Publishing %d events to topic %s failed: %s. Retrying in %v", len(events), topic, err.Error(), backoff)
+		select {
+		case <-c.cancel:
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > c.retry.Max {
+			backoff = c.retry.Max
+		}
+	}
+}
+
+// report logs the events dropped by the publish policy since the last tick
+// and updates the queue metric of the channel.
+func (c *Client) report() {
+	c.queued.Set(int64(len(*c.channel)))
+	if dropped := c.counters.dropped(); dropped != c.dropped {
+		log.Warn("Dropped %d events since last report, total %v", dropped-c.dropped, c.counters)
+		c.dropped = dropped
+	}
+}
+
 func (c *Client) Stop() {
-	c.producer.Stop()
-}
\ No newline at end of file
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
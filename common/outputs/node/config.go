@@ -1,10 +1,19 @@
 package node
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/spool"
+)
 
 type Config struct {
 	Timeout timeoutConfig
 	Publish publishConfig
+	Discovery discoveryConfig
+	Spool   spool.Config `config:"spool"`
+	Codec   codec.Config `config:"codec"`
 
 	URLs []string `config:urls`
 }
@@ -14,10 +23,84 @@ type timeoutConfig struct {
 	Request time.Duration
 }
 
+// discoveryConfig refreshes the producers from the URLs every Interval.
+type discoveryConfig struct {
+	Interval time.Duration
+}
+
 type publishConfig struct {
-	Group int
-	Size  int
-	Timer time.Duration
+	Group   int
+	Size    int
+	Timer   time.Duration
+	Retry   retryConfig
+	Policy  string
+	Timeout time.Duration
+	Batch   batchConfig
+	Compression string
+	// bounds how long a closed output keeps publishing the events it holds
+	Shutdown time.Duration
+}
+
+// batchConfig publishes the events of a topic together once Size events or
+// Bytes of encoded events are collected, or Linger passed since the first.
+type batchConfig struct {
+	Size   int           `validate:"min=1"`
+	Bytes  int           `validate:"min=0"`
+	Linger time.Duration `validate:"min=0"`
+}
+
+type retryConfig struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+var defaultConfig = Config{
+	Discovery: discoveryConfig{
+		Interval: 60 * time.Second,
+	},
+	Publish: publishConfig{
+		Retry: retryConfig{
+			Min: 1 * time.Second,
+			Max: 60 * time.Second,
+		},
+		Policy:  PolicyBlock,
+		Timeout: 1 * time.Second,
+		Batch: batchConfig{
+			Size:   1,
+			Bytes:  1024 * 1024,
+			Linger: 1 * time.Second,
+		},
+		Compression: codec.CompressionNone,
+		Shutdown: 5 * time.Second,
+	},
+	Spool: spool.DefaultConfig,
+	Codec: codec.DefaultConfig,
 }
 
-var defaultConfig = Config{}
\ No newline at end of file
+func (c *Config) Validate() error {
+	if len(c.URLs) == 0 {
+		return fmt.Errorf("At least one lookup URL is required")
+	}
+
+	if c.Discovery.Interval <= 0 {
+		return fmt.Errorf("Discovery interval must be greater than 0")
+	}
+
+	if _, ok := ValidPolicy[c.Publish.Policy]; !ok {
+		return fmt.Errorf("Invalid publish policy: %v", c.Publish.Policy)
+	}
+
+	if c.Publish.Policy == PolicyTimeout && c.Publish.Timeout <= 0 {
+		return fmt.Errorf("Publish timeout must be greater than 0 with the %v policy", PolicyTimeout)
+	}
+
+	if c.Publish.Batch.Size > 1 && c.Publish.Batch.Linger <= 0 {
+		return fmt.Errorf("Publish batch linger must be greater than 0 for batches of more than 1 event")
+	}
+
+	if _, ok := codec.ValidCompression[c.Publish.Compression]; !ok {
+		return fmt.Errorf("Invalid publish compression: %v", c.Publish.Compression)
+	}
+
+	return nil
+}
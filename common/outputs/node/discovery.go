@@ -0,0 +1,164 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/httpx"
+	"github.com/queueio/sentry/utils/encoding"
+)
+
+var ErrNoProducer = errors.New("no producer discovered")
+
+type producerInfo struct {
+	RemoteAddress string  `mapstructure:"remote_address"`
+	TCPPort       float64 `mapstructure:"tcp_port"`
+}
+
+// discovery keeps the producers announced by the lookup URLs. Groups are
+// spread over the producers starting at a position picked by the hostname,
+// so the agents of a cluster spread over the producers as well.
+type discovery struct {
+	sync.RWMutex
+	urls      []string
+	client   *httpx.Client
+	offset    int
+	addresses []string
+	version   uint64
+
+	interval  time.Duration
+	done      chan struct{}
+}
+
+func newDiscovery(config *Config, hostname string) *discovery {
+	return &discovery{
+		urls:     config.URLs,
+		client:   httpx.NewClient(nil, config.Timeout.Connect, config.Timeout.Request),
+		offset:   encoding.Hashcode(hostname),
+		interval: config.Discovery.Interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// refresh queries all URLs and keeps the union of the producers. If no URL
+// answers the producers known before are kept.
+func (d *discovery) refresh() error {
+	seen := map[string]struct{}{}
+	var addresses []string
+	var last error
+	answered := false
+
+	for _, url := range d.urls {
+		var v struct {
+			Producers []map[string]interface{} `json:"producers"`
+		}
+		if err := d.client.GETV1(url, &v); err != nil {
+			log.Warn("Querying producers from %s failed: %v", url, err)
+			last = err
+			continue
+		}
+		answered = true
+
+		for _, p := range v.Producers {
+			var info producerInfo
+			if err := mapstructure.Decode(p, &info); err != nil {
+				last = err
+				continue
+			}
+
+			address, err := info.address()
+			if err != nil {
+				last = err
+				continue
+			}
+			if _, ok := seen[address]; !ok {
+				seen[address] = struct{}{}
+				addresses = append(addresses, address)
+			}
+		}
+	}
+
+	if !answered {
+		return last
+	}
+	sort.Strings(addresses)
+
+	d.Lock()
+	defer d.Unlock()
+	if !equal(d.addresses, addresses) {
+		log.Info("Producers changed from %v to %v", d.addresses, addresses)
+		d.addresses = addresses
+		d.version++
+	}
+	return nil
+}
+
+func (d *discovery) run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			if err := d.refresh(); err != nil {
+				log.Err("Refreshing producers failed: %v", err)
+			}
+		}
+	}
+}
+
+func (d *discovery) stop() {
+	close(d.done)
+}
+
+// pick returns the producer of the group after the given number of
+// failovers, along with the version of the producer list.
+func (d *discovery) pick(group, failovers int) (string, uint64, bool) {
+	d.RLock()
+	defer d.RUnlock()
+
+	if len(d.addresses) == 0 {
+		return "", d.version, false
+	}
+	i := (d.offset + group + failovers) % len(d.addresses)
+	if i < 0 {
+		i += len(d.addresses)
+	}
+	return d.addresses[i], d.version, true
+}
+
+func (d *discovery) changed(version uint64) bool {
+	d.RLock()
+	defer d.RUnlock()
+	return d.version != version
+}
+
+func (p producerInfo) address() (string, error) {
+	host, _, err := net.SplitHostPort(p.RemoteAddress)
+	if err != nil {
+		return "", fmt.Errorf("invalid producer address %s: %v", p.RemoteAddress, err)
+	}
+	return net.JoinHostPort(host, strconv.FormatFloat(p.TCPPort, 'f', -1, 64)), nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,32 +1,36 @@
 package node
 
 import (
-	"time"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/satori/go.uuid"
-	"github.com/mitchellh/mapstructure"
 
 	"github.com/queueio/sentry/utils/job"
-	"github.com/queueio/sentry/utils/log"
 	"github.com/queueio/sentry/utils/types"
 	"github.com/queueio/sentry/utils/queue"
-	"github.com/queueio/sentry/utils/httpx"
 	"github.com/queueio/sentry/utils/config"
 	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/codec"
+	"github.com/queueio/sentry/utils/outputs/spool"
 	"github.com/queueio/sentry/utils/encoding"
 	"github.com/queueio/sentry/utils/component"
 	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/components/scribe/metric"
 )
 
 type node struct {
+	name      string
 	executor *job.Executor
 	channels  []*chan event.Event
+	counters  []*counters
 	n         int
-
-	RemoteAddress  string  `mapstructure:"remote_address"`
-	TCPPort        float64 `mapstructure:"tcp_port"`
+	policy    string
+	timeout   time.Duration
+	spool    *spool.Spools
+	discovery *discovery
+	metrics  *metric.Output
+	done      chan struct{}
 }
 
 func init() {
@@ -40,55 +44,108 @@ func open(info component.Info, config *config.Config) (queue.Handler, error) {
 		return outputs.Fail(err)
 	}
 
+	encoder, err := codec.New(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	name := outputs.Name(config, "node")
 	n := &node{
+        name:     name,
         executor: job.New(),
+        policy:   cfg.Publish.Policy,
+        timeout:  cfg.Publish.Timeout,
+        metrics:  metric.NewOutput(name),
+        done:     make(chan struct{}),
     }
 
-    var v interface{}
-    client := httpx.NewClient(nil, cfg.Timeout.Connect, cfg.Timeout.Request)
-    if err := client.GETV1(cfg.URLs[0], &v); err != nil {
-    	return nil, err
+	n.discovery = newDiscovery(&cfg, info.Hostname)
+	if err := n.discovery.refresh(); err != nil {
+		return nil, err
 	}
-
-	nodes := v.(map[string]interface{})["producers"].([]interface{})
-	if l := len(nodes); l > 0 {
-		if err := mapstructure.Decode(nodes[encoding.Hashcode(info.Hostname) % l], &n); err != nil {
-			return nil, err
-		}
+	if _, _, ok := n.discovery.pick(0, 0); !ok {
+		return nil, ErrNoProducer
 	}
+	go n.discovery.run()
 
-	port := strconv.FormatFloat(n.TCPPort, 'f', -1, 64)
 	for i := 0; i < cfg.Publish.Group; i++ {
-		producer, err := queue.NewProducer(n.RemoteAddress[:strings.Index(n.RemoteAddress, ":")+1]+port,
-											queue.NewConfig())
-		if err != nil {
-			log.Err(err.Error())
-			continue
-		}
-
 		channel := make(chan event.Event, cfg.Publish.Size)
+		counters := &counters{}
 		n.channels = append(n.channels, &channel)
+		n.counters = append(n.counters, counters)
+		// publisher groups share the channels, so the queue is reported
+		// per channel
+		labels := metric.Labels{"output": n.name, "channel": strconv.Itoa(i)}
 		n.executor.Start(&Client{
 			id:       uuid.NewV4(),
 			channel:  &channel,
 			timer:    time.NewTicker(cfg.Publish.Timer),
-			producer: producer,
+			retry:    cfg.Publish.Retry,
+			counters: counters,
+			codec:    encoder,
+			batch:    cfg.Publish.Batch,
+			compression: cfg.Publish.Compression,
+			batches:  map[string]*batch{},
+			group:    i,
+			discovery: n.discovery,
+			metrics:  n.metrics,
+			queued:   metric.NewGauge("output_channel_queued_events", "Events waiting in the channel.", labels),
+			shutdown: cfg.Publish.Shutdown,
+			done:     make(chan struct{}),
 		})
 	}
 
 	n.n = len(n.channels)
+
+	if cfg.Spool.Enabled {
+		n.spool, err = spool.New(n.name, cfg.Spool, n.group)
+		if err != nil {
+			// stops the discovery and the clients started above
+			n.Close()
+			return nil, err
+		}
+	}
+
 	return n, nil
 }
 
 func (n *node) Group(name string) types.Object {
+	if n.spool != nil {
+		return n.spool.Group(name)
+	}
+	return n.group(name)
+}
+
+func (n *node) group(name string) outputs.Publisher {
 	index := encoding.Hashcode(name) % n.n
 	return &publisher{
 		index: index,
 		channel: n.channels[index],
+		policy: n.policy,
+		timeout: n.timeout,
+		counters: n.counters[index],
+		metrics: n.metrics,
+		drops: metric.NewCounter("output_group_dropped_total", "Events the publish policy dropped in the group.",
+			metric.Labels{"output": n.name, "group": name}),
+		done: n.done,
 	}
 }
 
-func (n *node) Close() error { return nil }
+// Close stops the publishers first, so the clients and the drain of the
+// spool do not wait for each other on the channels. The clients stop before
+// the spool writes its checkpoint, so it holds all of their acks.
+func (n *node) Close() error {
+	close(n.done)
+	n.executor.Stop()
+
+	var err error
+	if n.spool != nil {
+		err = n.spool.Close()
+	}
+
+	n.discovery.stop()
+	return err
+}
 
 func (n *node) HandleMessage(message *queue.Message) error {
 	return nil
@@ -1,13 +1,112 @@
 package node
 
-import "github.com/queueio/sentry/utils/types/event"
+import (
+	"fmt"
+	"time"
+	"sync/atomic"
+
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+const (
+	PolicyBlock      = "block"
+	PolicyDropNewest = "drop_newest"
+	PolicyDropOldest = "drop_oldest"
+	PolicyTimeout    = "timeout"
+)
+
+var ValidPolicy = map[string]struct{}{
+	PolicyBlock:      {},
+	PolicyDropNewest: {},
+	PolicyDropOldest: {},
+	PolicyTimeout:    {},
+}
+
+// counters collects the events a group channel dropped because the client
+// behind it could not keep up.
+type counters struct {
+	newest  uint64
+	oldest  uint64
+	timeout uint64
+}
+
+func (c *counters) dropped() uint64 {
+	return atomic.LoadUint64(&c.newest) + atomic.LoadUint64(&c.oldest) + atomic.LoadUint64(&c.timeout)
+}
+
+func (c *counters) String() string {
+	return fmt.Sprintf("drop_newest=%d drop_oldest=%d timeout=%d",
+		atomic.LoadUint64(&c.newest), atomic.LoadUint64(&c.oldest), atomic.LoadUint64(&c.timeout))
+}
 
 type publisher struct {
 	channel  *chan event.Event
 	index    int
+	policy   string
+	timeout  time.Duration
+	counters *counters
+	metrics  *metric.Output
+	drops    *metric.Counter
+	done     <-chan struct{} // closed once the output is closed
 }
 
+// Publish hands the event to the client of the group. Events dropped by the
+// policy are acknowledged right away, so the collectors move on instead of
+// waiting for the output.
 func (p *publisher) Publish(e event.Event) error {
-	*p.channel <- e
+	switch p.policy {
+	case PolicyDropNewest:
+		select {
+		case *p.channel <- e:
+			p.metrics.Published.Inc()
+		default:
+			atomic.AddUint64(&p.counters.newest, 1)
+			p.metrics.Dropped.Inc()
+			p.drops.Inc()
+			outputs.ACK(e)
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case *p.channel <- e:
+				p.metrics.Published.Inc()
+				return nil
+			default:
+			}
+
+			select {
+			case old := <-*p.channel:
+				atomic.AddUint64(&p.counters.oldest, 1)
+				p.metrics.Dropped.Inc()
+				p.drops.Inc()
+				outputs.ACK(old)
+			default:
+			}
+		}
+	case PolicyTimeout:
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+
+		select {
+		case *p.channel <- e:
+			p.metrics.Published.Inc()
+		case <-p.done:
+			return outputs.ErrClosed
+		case <-timer.C:
+			atomic.AddUint64(&p.counters.timeout, 1)
+			p.metrics.Dropped.Inc()
+			p.drops.Inc()
+			outputs.ACK(e)
+		}
+	default:
+		select {
+		case *p.channel <- e:
+			p.metrics.Published.Inc()
+		case <-p.done:
+			return outputs.ErrClosed
+		}
+	}
 	return nil
 }
@@ -1,6 +1,8 @@
 package outputs
 
 import (
+	"sync"
+
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/types/event"
 	"github.com/queueio/sentry/utils/types"
@@ -16,11 +18,66 @@ type Publisher interface {
 	Publish(e event.Event) error
 }
 
+// Signaler is stored in event.Private by publishers which need to know when
+// an output is done with the event.
+type Signaler interface {
+	Completed()
+}
+
+// ACK tells the publishers of the events that the output delivered them, or
+// gave up on them for good. Outputs must ACK events in the order they got them.
+func ACK(events ...event.Event) {
+	for _, e := range events {
+		if s, ok := e.Private.(Signaler); ok {
+			s.Completed()
+		}
+	}
+}
+
+// Deferred is implemented by outputs which deliver messages after
+// HandleMessage returned. They call Done for every message once it was
+// delivered or written to the dead letters.
+type Deferred interface {
+	Deferred()
+}
+
+// tracked holds the private data of the events messages were built from,
+// until the output is done with them.
+var tracked sync.Map
+
+// Track remembers the private data of the event the message was built from,
+// it is signaled by Done.
+func Track(message *queue.Message, private interface{}) {
+	if private != nil {
+		tracked.Store(message, private)
+	}
+}
+
+// Done ACKs the events the messages were built from. Messages not tracked,
+// as those from the queue, are ignored.
+func Done(messages ...*queue.Message) {
+	for _, m := range messages {
+		if private, ok := tracked.Load(m); ok {
+			tracked.Delete(m)
+			ACK(event.Event{Private: private})
+		}
+	}
+}
+
 type OutputAdapter interface {
 	Group(name string) types.Object
 }
 
-func GroupPublish(name string, handler queue.Handler) Publisher {
-    adapter := handler.(OutputAdapter)
-    return adapter.Group(name).(Publisher)
+// GroupPublish returns the publisher the output uses for the named group.
+func GroupPublish(name string, handler queue.Handler) (Publisher, error) {
+	adapter, ok := handler.(OutputAdapter)
+	if !ok {
+		return nil, ErrNotGrouped
+	}
+
+	publisher, ok := adapter.Group(name).(Publisher)
+	if !ok {
+		return nil, ErrNotGrouped
+	}
+	return publisher, nil
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/queueio/sentry/utils/queue"
 	"github.com/queueio/sentry/utils/config"
@@ -10,8 +11,21 @@ import (
 
 var output = map[string]Factory{}
 
+var setup = map[string]SetupFactory{}
+
 type Factory func(info component.Info, config *config.Config) (queue.Handler, error)
 
+// SetupOptions control how outputs prepare their destination.
+type SetupOptions struct {
+	Overwrite bool      // replace resources which exist already
+	DryRun    bool      // only report what would be done
+	Writer    io.Writer // receives the report of the setup, logged if nil
+}
+
+// SetupFactory prepares the destination of an output, like loading index
+// templates, before events are shipped to it.
+type SetupFactory func(info component.Info, config *config.Config, options SetupOptions) error
+
 func Register(name string, f Factory) {
 	if output[name] != nil {
 		panic(fmt.Errorf("output type '%v' exists already", name))
@@ -30,4 +44,21 @@ func Load(info component.Info, name string, config *config.Config) (queue.Handle
 	}
 
 	return factory(info, config)
-}
\ No newline at end of file
+}
+func RegisterSetup(name string, f SetupFactory) {
+	if setup[name] != nil {
+		panic(fmt.Errorf("output setup '%v' exists already", name))
+	}
+	setup[name] = f
+}
+
+// Setup prepares the destination of the output, outputs without setup have
+// nothing to prepare.
+func Setup(info component.Info, name string, config *config.Config, options SetupOptions) error {
+	factory := setup[name]
+	if factory == nil {
+		return nil
+	}
+
+	return factory(info, config, options)
+}
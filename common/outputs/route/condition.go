@@ -0,0 +1,105 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common/match"
+
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+var ErrEmptyCondition = errors.New("condition without any check")
+
+// ConditionConfig selects events by their fields. All checks given in one
+// condition must hold.
+type ConditionConfig struct {
+	Equals    map[string]interface{}   `config:"equals"`
+	Contains  map[string]string        `config:"contains"`
+	Regexp    map[string]match.Matcher `config:"regexp"`
+	HasFields []string                 `config:"has_fields"`
+	And       []ConditionConfig        `config:"and"`
+	Or        []ConditionConfig        `config:"or"`
+	Not       *ConditionConfig         `config:"not"`
+}
+
+func (c *ConditionConfig) Validate() error {
+	if len(c.Equals) == 0 && len(c.Contains) == 0 && len(c.Regexp) == 0 &&
+		len(c.HasFields) == 0 && len(c.And) == 0 && len(c.Or) == 0 && c.Not == nil {
+		return ErrEmptyCondition
+	}
+	return nil
+}
+
+// Check tells whether the event matches the condition.
+func (c *ConditionConfig) Check(e *event.Event) bool {
+	for field, want := range c.Equals {
+		value, err := e.GetValue(field)
+		if err != nil || fmt.Sprint(value) != fmt.Sprint(want) {
+			return false
+		}
+	}
+
+	for field, want := range c.Contains {
+		value, ok := stringValue(e, field)
+		if !ok || !strings.Contains(value, want) {
+			return false
+		}
+	}
+
+	for field, matcher := range c.Regexp {
+		value, ok := stringValue(e, field)
+		if !ok || !matcher.MatchString(value) {
+			return false
+		}
+	}
+
+	for _, field := range c.HasFields {
+		if _, err := e.GetValue(field); err != nil {
+			return false
+		}
+	}
+
+	for i := range c.And {
+		if !c.And[i].Check(e) {
+			return false
+		}
+	}
+
+	if len(c.Or) > 0 {
+		matched := false
+		for i := range c.Or {
+			if c.Or[i].Check(e) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if c.Not != nil && c.Not.Check(e) {
+		return false
+	}
+
+	return true
+}
+
+func stringValue(e *event.Event, field string) (string, bool) {
+	value, err := e.GetValue(field)
+	if err != nil {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return fmt.Sprint(value), true
+}
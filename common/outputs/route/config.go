@@ -0,0 +1,21 @@
+package route
+
+import (
+	"errors"
+)
+
+var ErrNoName = errors.New("route without output name")
+
+// Config routes the events of an input to the named output. Without a
+// condition all events are routed.
+type Config struct {
+	Name string           `config:"name"`
+	When *ConditionConfig `config:"when"`
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return ErrNoName
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package route
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+type target struct {
+	name string
+	when *ConditionConfig
+	out  outputs.Publisher
+}
+
+// fanout publishes each event to the targets whose condition it matches.
+type fanout struct {
+	targets []target
+}
+
+func (f *fanout) Publish(e event.Event) error {
+	var matched []target
+	for _, t := range f.targets {
+		if t.when == nil || t.when.Check(&e) {
+			matched = append(matched, t)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		outputs.ACK(e)
+		return nil
+	case 1:
+		return matched[0].out.Publish(e)
+	}
+
+	// the publisher of the event is signaled once all outputs are done
+	s := &split{count: int32(len(matched)), private: e.Private}
+	e.Private = s
+
+	var (
+		failed int
+		err    error
+	)
+	for _, t := range matched {
+		if perr := t.out.Publish(e); perr != nil {
+			log.Err("Failed to publish event to output %s: %v", t.name, perr)
+			failed++
+			err = perr
+		}
+	}
+
+	// nothing got published, leave it to the publisher to handle the error
+	if failed == len(matched) {
+		return err
+	}
+
+	for i := 0; i < failed; i++ {
+		s.Completed()
+	}
+	return nil
+}
+
+// split signals the original publisher of an event published to several
+// outputs when the last of them is done.
+type split struct {
+	count   int32
+	private interface{}
+}
+
+func (s *split) Completed() {
+	if atomic.AddInt32(&s.count, -1) == 0 {
+		outputs.ACK(event.Event{Private: s.private})
+	}
+}
+
+// direct publishes events to outputs without groups by handing them over as
+// messages, the way they would arrive from the queue.
+type direct struct {
+	name string
+	out  queue.Handler
+}
+
+func newDirect(name string, out queue.Handler) *direct {
+	return &direct{name: name, out: out}
+}
+
+func (d *direct) Publish(e event.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Err("Dropping event for output %s which cannot be encoded: %v", d.name, err)
+		outputs.ACK(e)
+		return nil
+	}
+
+	message := &queue.Message{
+		Body:      body,
+		Timestamp: time.Now().UnixNano(),
+	}
+	var id [len(message.ID) / 2]byte
+	rand.Read(id[:])
+	hex.Encode(message.ID[:], id[:])
+
+	// outputs delivering in the background ACK the event once they are done,
+	// the others are done when HandleMessage returns
+	if _, ok := d.out.(outputs.Deferred); ok {
+		outputs.Track(message, e.Private)
+	} else {
+		defer outputs.ACK(e)
+	}
+
+	if err := d.out.HandleMessage(message); err != nil {
+		log.Err("Output %s failed to handle event: %v", d.name, err)
+		if logger, ok := d.out.(queue.FailedMessageLogger); ok {
+			logger.LogFailedMessage(message)
+		}
+		outputs.Done(message)
+	}
+	return nil
+}
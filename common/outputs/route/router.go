@@ -0,0 +1,117 @@
+package route
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/queue"
+)
+
+// Router holds the named outputs of an instance. Messages handed to the
+// router go to every output, inputs pick their outputs through Publisher.
+type Router struct {
+	names   []string
+	outputs map[string]queue.Handler
+}
+
+func New(handlers map[string]queue.Handler) *Router {
+	r := &Router{outputs: handlers}
+	for name := range handlers {
+		r.names = append(r.names, name)
+	}
+	sort.Strings(r.names)
+	return r
+}
+
+// Names returns the names of all outputs, sorted.
+func (r *Router) Names() []string {
+	return r.names
+}
+
+// Output returns the named output, or nil if there is none.
+func (r *Router) Output(name string) queue.Handler {
+	return r.outputs[name]
+}
+
+func (r *Router) HandleMessage(message *queue.Message) error {
+	var failed []string
+	for _, name := range r.names {
+		if err := r.outputs[name].HandleMessage(message); err != nil {
+			log.Err("Output %s failed to handle message: %v", name, err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("outputs %s failed to handle message", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (r *Router) LogFailedMessage(message *queue.Message) {
+	for _, name := range r.names {
+		if logger, ok := r.outputs[name].(queue.FailedMessageLogger); ok {
+			logger.LogFailedMessage(message)
+		}
+	}
+}
+
+func (r *Router) Close() error {
+	var err error
+	for _, name := range r.names {
+		if closer, ok := r.outputs[name].(io.Closer); ok {
+			if e := closer.Close(); e != nil {
+				log.Err("Failed to close output %s: %v", name, e)
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Publisher returns the publisher for the events of the named input. When the
+// handler is a router, events go to the outputs picked by the routes, or to
+// all outputs if the input has no routes. Otherwise the input publishes into
+// the group of the single output, or directly to it if it has no groups.
+func Publisher(group string, handler queue.Handler, routes []Config) (outputs.Publisher, error) {
+	router, ok := handler.(*Router)
+	if !ok {
+		if len(routes) > 0 {
+			return nil, fmt.Errorf("input %s has outputs configured, but only a single output exists", group)
+		}
+		publisher, err := outputs.GroupPublish(group, handler)
+		if err == outputs.ErrNotGrouped {
+			return newDirect("output", handler), nil
+		}
+		return publisher, err
+	}
+
+	if len(routes) == 0 {
+		for _, name := range router.names {
+			routes = append(routes, Config{Name: name})
+		}
+	}
+
+	f := &fanout{}
+	for _, route := range routes {
+		output := router.Output(route.Name)
+		if output == nil {
+			return nil, fmt.Errorf("input %s routes to unknown output %s", group, route.Name)
+		}
+
+		publisher, err := outputs.GroupPublish(group, output)
+		if err == outputs.ErrNotGrouped {
+			publisher, err = newDirect(route.Name, output), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		f.targets = append(f.targets, target{name: route.Name, when: route.When, out: publisher})
+	}
+	return f, nil
+}
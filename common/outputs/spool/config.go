@@ -0,0 +1,76 @@
+package spool
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	FsyncAlways   = "always"
+	FsyncInterval = "interval"
+	FsyncNever    = "never"
+)
+
+type Config struct {
+	Enabled bool          `config:"enabled"`
+	Path    string        `config:"path"`
+	Segment segmentConfig `config:"segment"`
+	Max     maxConfig     `config:"max"`
+	Fsync   fsyncConfig   `config:"fsync"`
+}
+
+type segmentConfig struct {
+	Size int64 `config:"size" validate:"min=1"`
+}
+
+type maxConfig struct {
+	Size int64 `config:"size" validate:"min=0"`
+}
+
+// fsyncConfig picks when segments are synced to disk: always after every
+// event, or every Interval. Events are acknowledged to their publisher once
+// synced, so interval delays them by up to Interval. never leaves syncing to
+// the OS and acknowledges events once written, a crash of the host loses
+// those the OS did not write yet.
+type fsyncConfig struct {
+	Policy   string        `config:"policy"`
+	Interval time.Duration `config:"interval" validate:"min=0"`
+}
+
+var DefaultConfig = Config{
+	Path: "spool",
+	Segment: segmentConfig{
+		Size: 16 * 1024 * 1024,
+	},
+	Max: maxConfig{
+		Size: 1024 * 1024 * 1024,
+	},
+	Fsync: fsyncConfig{
+		Policy:   FsyncInterval,
+		Interval: 1 * time.Second,
+	},
+}
+
+var ValidFsync = map[string]struct{}{
+	FsyncAlways:   {},
+	FsyncInterval: {},
+	FsyncNever:    {},
+}
+
+func (c *Config) Validate() error {
+	if _, ok := ValidFsync[c.Fsync.Policy]; !ok {
+		return fmt.Errorf("Invalid spool fsync policy: %v", c.Fsync.Policy)
+	}
+
+	if c.Fsync.Policy == FsyncInterval && c.Fsync.Interval <= 0 {
+		return fmt.Errorf("spool fsync interval must be > 0 when the interval policy is used")
+	}
+
+	// the segment being written to is only released once the next one was
+	// started, so the spool must hold at least two segments
+	if c.Max.Size > 0 && c.Max.Size < 2*c.Segment.Size {
+		return fmt.Errorf("spool max size (%d) must be at least twice the segment size (%d)", c.Max.Size, c.Segment.Size)
+	}
+
+	return nil
+}
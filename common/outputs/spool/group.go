@@ -0,0 +1,116 @@
+package spool
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/paths"
+)
+
+// Spools keeps one spool per publishing group of an output, below
+// <paths.data>/<config.path>/<output>/<group>.
+type Spools struct {
+	sync.Mutex
+	path   string
+	config Config
+	group  func(name string) outputs.Publisher
+	spools map[string]*Spool
+}
+
+// New opens the spools left over from a previous run, so their events are
+// drained even if no collector publishes into the group anymore. group
+// returns the output publisher a spool drains into.
+func New(output string, config Config, group func(name string) outputs.Publisher) (*Spools, error) {
+	s := &Spools{
+		path:   paths.Resolve(paths.Data, filepath.Join(config.Path, output)),
+		config: config,
+		group:  group,
+		spools: map[string]*Spool{},
+	}
+
+	infos, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return s, nil
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		name, err := groupName(info.Name())
+		if err != nil {
+			log.Warn("Ignoring spool dir %s: %v", info.Name(), err)
+			continue
+		}
+		if _, err := s.open(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Group returns the spool in front of the named group.
+func (s *Spools) Group(name string) outputs.Publisher {
+	spool, err := s.open(name)
+	if err != nil {
+		log.Err("Failed to open spool for %s, publishing without it: %v", name, err)
+		return s.group(name)
+	}
+	return spool
+}
+
+func (s *Spools) open(name string) (*Spool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if spool, ok := s.spools[name]; ok {
+		return spool, nil
+	}
+
+	spool, err := open(name, filepath.Join(s.path, dirName(name)), s.config, s.group(name))
+	if err != nil {
+		return nil, err
+	}
+	s.spools[name] = spool
+	return spool, nil
+}
+
+func (s *Spools) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	var err error
+	for name, spool := range s.spools {
+		if e := spool.Close(); e != nil {
+			log.Err("Failed to close spool %s: %v", name, e)
+			err = e
+		}
+	}
+	return err
+}
+
+// dirName escapes the group name for use as directory name, groupName turns
+// it back into the name of the group.
+func dirName(group string) string {
+	name := url.PathEscape(group)
+	switch name {
+	case "", ".", "..":
+		// not usable as directory names, escaping never yields %00
+		return strings.Replace(name, ".", "%2E", -1) + "%00"
+	}
+	return name
+}
+
+func groupName(dir string) (string, error) {
+	name, err := url.PathUnescape(strings.TrimSuffix(dir, "%00"))
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
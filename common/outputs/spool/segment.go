@@ -0,0 +1,113 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A segment is a file of records, each made of a 4 byte big endian payload
+// length, the 4 byte CRC-32C of the payload and the payload itself.
+const (
+	headerSize = 8
+	segmentExt = ".seg"
+)
+
+var (
+	ErrCorrupt  = errors.New("corrupt spool record")
+	ErrTooLarge = errors.New("event exceeds spool segment size")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+func segmentName(id uint64) string {
+	return fmt.Sprintf("%020d%s", id, segmentExt)
+}
+
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+	return id, err == nil
+}
+
+func writeRecord(w io.Writer, payload []byte) (int, error) {
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[headerSize:], payload)
+	return w.Write(buf)
+}
+
+// readRecord returns the payload of the next record and the number of bytes
+// it takes in the segment. io.EOF is only returned on a record boundary,
+// anything else which does not check out is reported as ErrCorrupt.
+func readRecord(r io.Reader, max int64) ([]byte, int, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, ErrCorrupt
+		}
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if int64(size) > max {
+		return nil, 0, ErrCorrupt
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, 0, ErrCorrupt
+		}
+		return nil, 0, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, ErrCorrupt
+	}
+
+	return payload, headerSize + int(size), nil
+}
+
+// recoverSegment truncates the segment after its last valid record, which
+// drops records half written by a crash. It returns the new segment size.
+func recoverSegment(path string, max int64) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		_, n, err := readRecord(r, max)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err == ErrCorrupt {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(n)
+	}
+
+	if err := f.Truncate(offset); err != nil {
+		return 0, err
+	}
+	return offset, f.Sync()
+}
@@ -0,0 +1,580 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+const checkpointFile = "checkpoint"
+
+// position points into a segment, right behind a record.
+type position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Spool is an on-disk queue in front of a publisher. Events are appended to
+// segment files and acknowledged to their publisher once synced to disk, or
+// right away with the never fsync policy, which loses what the page cache
+// held on a crash. A reader drains them in order into the output. Segments
+// are removed once the output acknowledged all of their events, the read
+// position survives restarts through the checkpoint file.
+type Spool struct {
+	sync.Mutex
+	cond   *sync.Cond
+	name   string
+	path   string
+	config Config
+	out    outputs.Publisher
+
+	segments []uint64
+	sizes    map[uint64]int64
+	size     int64
+	full     bool
+
+	writer    *os.File
+	writeID   uint64
+	writeSize int64
+	dirty     bool
+	unsynced  []event.Event // written, acknowledged by the next flush
+
+	reader   *os.File
+	buffer   *bufio.Reader
+	read     position
+	acked    position
+	inflight []*pending
+	changed  bool
+
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// pending is stored in event.Private of the events drained from the spool.
+type pending struct {
+	spool *Spool
+	pos   position
+	acked bool
+}
+
+func (p *pending) Completed() {
+	p.spool.ack(p)
+}
+
+func open(name, path string, config Config, out outputs.Publisher) (*Spool, error) {
+	s := &Spool{
+		name:   name,
+		path:   path,
+		config: config,
+		out:    out,
+		sizes:  map[uint64]int64{},
+		done:   make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.Mutex)
+
+	if err := os.MkdirAll(path, 0750); err != nil {
+		return nil, fmt.Errorf("Failed to create spool dir %s: %v", path, err)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	log.Info("Spool %s opened with %d segments, %d bytes: %s", name, len(s.segments), s.size, path)
+
+	s.wg.Add(2)
+	go s.sync()
+	go s.run()
+
+	return s, nil
+}
+
+// load picks up the segments and the checkpoint left by a previous run.
+func (s *Spool) load() error {
+	infos, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if id, ok := parseSegmentName(info.Name()); ok {
+			s.segments = append(s.segments, id)
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	if err := s.loadCheckpoint(); err != nil {
+		log.Err("Spool %s checkpoint ignored: %v", s.name, err)
+		s.acked = position{}
+	}
+
+	segments := s.segments[:0]
+	for _, id := range s.segments {
+		if id < s.acked.Segment {
+			os.Remove(s.segmentPath(id))
+			continue
+		}
+		segments = append(segments, id)
+	}
+	s.segments = segments
+
+	if len(s.segments) == 0 {
+		s.writeID = s.acked.Segment
+		s.read = position{Segment: s.writeID + 1}
+		s.acked = s.read
+		return nil
+	}
+
+	for i, id := range s.segments {
+		size, err := s.recover(id, i == len(s.segments)-1)
+		if err != nil {
+			return err
+		}
+		s.sizes[id] = size
+		s.size += size
+	}
+
+	if s.acked.Segment != s.segments[0] || s.acked.Offset > s.sizes[s.segments[0]] {
+		s.acked = position{Segment: s.segments[0]}
+	}
+
+	s.read = s.acked
+	s.writeID = s.segments[len(s.segments)-1]
+	s.writeSize = s.sizes[s.writeID]
+	return nil
+}
+
+func (s *Spool) recover(id uint64, last bool) (int64, error) {
+	path := s.segmentPath(id)
+	if !last {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+
+	size, err := recoverSegment(path, s.config.Segment.Size)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to recover spool segment %s: %v", path, err)
+	}
+	return size, nil
+}
+
+func (s *Spool) loadCheckpoint() error {
+	data, err := ioutil.ReadFile(filepath.Join(s.path, checkpointFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.acked)
+}
+
+func (s *Spool) writeCheckpoint(pos position) error {
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.path, checkpointFile)
+	tempfile := path + ".new"
+	if err := ioutil.WriteFile(tempfile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempfile, path)
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.path, segmentName(id))
+}
+
+// Publish writes the event to disk and acknowledges it to its publisher. It
+// blocks while the spool is at its size limit.
+func (s *Spool) Publish(e event.Event) error {
+	private := e.Private
+	e.Private = nil
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Err("Dropping event which cannot be spooled: %v", err)
+		e.Private = private
+		outputs.ACK(e)
+		return nil
+	}
+
+	if int64(len(payload)) > s.config.Segment.Size {
+		log.Err("Dropping event of %d bytes: %v", len(payload), ErrTooLarge)
+		e.Private = private
+		outputs.ACK(e)
+		return nil
+	}
+
+	s.Lock()
+	for !s.closed && s.size > 0 && s.config.Max.Size > 0 &&
+		s.size+int64(len(payload)+headerSize) > s.config.Max.Size {
+		if !s.full {
+			log.Warn("Spool %s reached its size limit of %d bytes. Blocking publishers.", s.name, s.config.Max.Size)
+			s.full = true
+		}
+
+		// The segment being written to is never removed, move on to a new
+		// one so it is released once drained.
+		if s.writer != nil && s.writeSize > 0 {
+			if err := s.rotate(); err != nil {
+				s.Unlock()
+				return err
+			}
+		}
+		s.cond.Wait()
+	}
+
+	if s.closed {
+		s.Unlock()
+		return outputs.ErrClosed
+	}
+
+	s.full = false
+	err = s.write(payload)
+	e.Private = private
+	synced := s.config.Fsync.Policy != FsyncInterval
+	if err == nil && !synced {
+		s.unsynced = append(s.unsynced, event.Event{Private: private})
+	}
+	s.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if synced {
+		outputs.ACK(e)
+	}
+	return nil
+}
+
+func (s *Spool) write(payload []byte) error {
+	if s.writer == nil || s.writeSize >= s.config.Segment.Size {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := writeRecord(s.writer, payload)
+	if err == nil && s.config.Fsync.Policy == FsyncAlways {
+		err = s.writer.Sync()
+	}
+	if err != nil {
+		// cut off whatever made it to disk, the record is rejected and
+		// neither counted nor drained
+		s.writer.Truncate(s.writeSize)
+		return err
+	}
+
+	s.writeSize += int64(n)
+	s.sizes[s.writeID] = s.writeSize
+	s.size += int64(n)
+	if s.config.Fsync.Policy != FsyncAlways {
+		s.dirty = true
+	}
+
+	s.cond.Broadcast()
+	return nil
+}
+
+func (s *Spool) rotate() error {
+	if s.writer != nil {
+		if s.config.Fsync.Policy != FsyncNever {
+			s.writer.Sync()
+		}
+		s.writer.Close()
+	}
+
+	id := s.writeID + 1
+	f, err := os.OpenFile(s.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to create spool segment: %v", err)
+	}
+
+	log.Debug("spool", "Spool %s rotated to segment %d", s.name, id)
+
+	s.writer = f
+	s.writeID = id
+	s.writeSize = 0
+	s.dirty = false
+	s.segments = append(s.segments, id)
+	s.sizes[id] = 0
+	s.cond.Broadcast()
+	return nil
+}
+
+// run drains the spool into the output.
+func (s *Spool) run() {
+	defer s.wg.Done()
+
+	for {
+		payload, pos, ok := s.next()
+		if !ok {
+			return
+		}
+
+		var e event.Event
+		if err := json.Unmarshal(payload, &e); err != nil {
+			log.Err("Skipping spooled event which cannot be decoded: %v", err)
+			s.ack(s.track(pos))
+			continue
+		}
+
+		e.Private = s.track(pos)
+		for {
+			err := s.out.Publish(e)
+			if err == nil {
+				break
+			}
+
+			log.Err("Publishing spooled event failed: %v. Retrying.", err)
+			select {
+			case <-s.done:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// next reads the record at the read position, waiting for it to be written
+// if needed. Corrupt segments are skipped from the first bad record on.
+func (s *Spool) next() ([]byte, position, bool) {
+	for {
+		s.Lock()
+		for !s.closed && (s.read.Segment > s.writeID ||
+			(s.read.Segment == s.writeID && s.read.Offset >= s.writeSize)) {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.Unlock()
+			s.closeReader()
+			return nil, position{}, false
+		}
+		s.Unlock()
+
+		if s.reader == nil {
+			if err := s.openReader(); err != nil {
+				log.Err("Failed to open spool segment %d: %v", s.read.Segment, err)
+				s.skip()
+				continue
+			}
+		}
+
+		payload, n, err := readRecord(s.buffer, s.config.Segment.Size)
+		switch err {
+		case nil:
+			s.read.Offset += int64(n)
+			return payload, s.read, true
+		case io.EOF:
+			s.Lock()
+			current := s.read.Segment == s.writeID
+			s.Unlock()
+			if !current {
+				s.skip()
+			}
+		case ErrCorrupt:
+			log.Err("Spool %s segment %d is corrupt at offset %d. Skipping the rest of the segment.",
+				s.name, s.read.Segment, s.read.Offset)
+			s.Lock()
+			if s.read.Segment == s.writeID {
+				if err := s.rotate(); err != nil {
+					log.Err("Spool %s: %v", s.name, err)
+				}
+			}
+			s.Unlock()
+			s.skip()
+		default:
+			log.Err("Failed reading spool segment %d: %v", s.read.Segment, err)
+			s.closeReader()
+			select {
+			case <-s.done:
+				return nil, position{}, false
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (s *Spool) openReader() error {
+	f, err := os.Open(s.segmentPath(s.read.Segment))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(s.read.Offset, os.SEEK_SET); err != nil {
+		f.Close()
+		return err
+	}
+	s.reader = f
+	s.buffer = bufio.NewReader(f)
+	return nil
+}
+
+func (s *Spool) closeReader() {
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+		s.buffer = nil
+	}
+}
+
+// skip moves the read position to the start of the next segment. The skipped
+// position is tracked like an event, so the segment gets released once all
+// events read before were acknowledged.
+func (s *Spool) skip() {
+	s.closeReader()
+
+	s.Lock()
+	next := s.read.Segment + 1
+	for _, id := range s.segments {
+		if id > s.read.Segment {
+			next = id
+			break
+		}
+	}
+	s.read = position{Segment: next}
+	s.Unlock()
+
+	s.ack(s.track(s.read))
+}
+
+func (s *Spool) track(pos position) *pending {
+	s.Lock()
+	defer s.Unlock()
+
+	p := &pending{spool: s, pos: pos}
+	s.inflight = append(s.inflight, p)
+	return p
+}
+
+func (s *Spool) ack(p *pending) {
+	s.Lock()
+	defer s.Unlock()
+
+	p.acked = true
+
+	n := 0
+	for ; n < len(s.inflight) && s.inflight[n].acked; n++ {
+		s.acked = s.inflight[n].pos
+	}
+	if n == 0 {
+		return
+	}
+	s.inflight = s.inflight[n:]
+	s.changed = true
+
+	// remove all segments before the acknowledged one, except for the one
+	// being written to
+	segments := s.segments[:0]
+	for _, id := range s.segments {
+		if id < s.acked.Segment && id != s.writeID {
+			if err := os.Remove(s.segmentPath(id)); err != nil {
+				log.Err("Failed to remove spool segment %d: %v", id, err)
+			}
+			s.size -= s.sizes[id]
+			delete(s.sizes, id)
+			continue
+		}
+		segments = append(segments, id)
+	}
+	s.segments = segments
+	s.cond.Broadcast()
+}
+
+// sync periodically flushes the written segment and the checkpoint to disk.
+func (s *Spool) sync() {
+	defer s.wg.Done()
+
+	interval := s.config.Fsync.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush syncs the segment being written to and acknowledges the events
+// written since the last flush. Segments written before were synced when the
+// spool rotated away from them.
+func (s *Spool) flush() {
+	s.Lock()
+	var synced []event.Event
+	if s.dirty && s.writer != nil && s.config.Fsync.Policy == FsyncInterval {
+		if err := s.writer.Sync(); err != nil {
+			log.Err("Failed to sync spool segment %d: %v", s.writeID, err)
+		} else {
+			s.dirty = false
+		}
+	}
+	if !s.dirty {
+		synced, s.unsynced = s.unsynced, nil
+	}
+
+	changed, acked := s.changed, s.acked
+	s.changed = false
+	s.Unlock()
+
+	outputs.ACK(synced...)
+
+	if changed {
+		if err := s.writeCheckpoint(acked); err != nil {
+			log.Err("Failed to write spool checkpoint: %v", err)
+		}
+	}
+}
+
+func (s *Spool) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.cond.Broadcast()
+	s.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+
+	s.Lock()
+	var synced []event.Event
+	if s.writer != nil {
+		if s.config.Fsync.Policy == FsyncNever || s.writer.Sync() == nil {
+			synced, s.unsynced = s.unsynced, nil
+		}
+		s.writer.Close()
+		s.writer = nil
+	}
+	err := s.writeCheckpoint(s.acked)
+	s.Unlock()
+
+	outputs.ACK(synced...)
+	return err
+}
@@ -0,0 +1,143 @@
+// +build !integration
+
+package spool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/types/event"
+)
+
+// testPublisher collects the events drained from the spool without
+// acknowledging them.
+type testPublisher struct {
+	events chan event.Event
+}
+
+func (p *testPublisher) Publish(e event.Event) error {
+	p.events <- e
+	return nil
+}
+
+func (p *testPublisher) receive(t *testing.T, n int) []string {
+	var topics []string
+	for len(topics) < n {
+		select {
+		case e := <-p.events:
+			topics = append(topics, e.Topic)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d events", len(topics), n)
+		}
+	}
+	return topics
+}
+
+func testConfig() Config {
+	config := DefaultConfig
+	config.Segment.Size = 1024
+	config.Max.Size = 0
+	config.Fsync.Policy = FsyncAlways
+	return config
+}
+
+func lastSegment(t *testing.T, dir string) string {
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil || len(segments) == 0 {
+		t.Fatalf("no spool segments in %s: %v", dir, err)
+	}
+	return segments[len(segments)-1]
+}
+
+func appendFile(t *testing.T, path string, data []byte) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpoolReopenAfterCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := &testPublisher{events: make(chan event.Event, 10)}
+	s, err := open("test", dir, testConfig(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, topic := range []string{"a", "b", "c"} {
+		assert.NoError(t, s.Publish(event.Event{Topic: topic}))
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, out.receive(t, 3))
+	assert.NoError(t, s.Close())
+
+	// a record half written when the process died
+	path := lastSegment(t, dir)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record bytes.Buffer
+	writeRecord(&record, []byte(`{"Topic":"d"}`))
+	appendFile(t, path, record.Bytes()[:record.Len()-3])
+
+	// the events were not acknowledged, they are drained again
+	out = &testPublisher{events: make(chan event.Event, 10)}
+	s, err = open("test", dir, testConfig(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, out.receive(t, 3))
+
+	assert.NoError(t, s.Publish(event.Event{Topic: "e"}))
+	assert.Equal(t, []string{"e"}, out.receive(t, 1))
+	assert.NoError(t, s.Close())
+
+	recovered, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the torn record was cut off, new events went to the next segment
+	assert.Equal(t, info.Size(), recovered.Size())
+}
+
+func TestRecoverSegmentCorruptTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var valid, corrupt bytes.Buffer
+	writeRecord(&valid, []byte("first"))
+	writeRecord(&valid, []byte("second"))
+	writeRecord(&corrupt, []byte("third"))
+	data := corrupt.Bytes()
+	data[len(data)-1] ^= 0xFF // the CRC does not match anymore
+
+	path := filepath.Join(dir, segmentName(1))
+	if err := ioutil.WriteFile(path, append(valid.Bytes(), data...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := recoverSegment(path, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(valid.Len()), size)
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, valid.Bytes(), content)
+}
@@ -2,13 +2,64 @@ package outputs
 
 import (
 	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/outputs/codec"
 	"errors"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
 )
 
 var (
     ErrEmpty  = errors.New("channel was empty")
     ErrFull   = errors.New("channel was full")
     ErrClosed = errors.New("channel closed")
+    ErrAttempts = errors.New("message exceeded the maximum delivery attempts")
+    ErrNotGrouped = errors.New("output does not support group publishing")
 )
 
 func Fail(err error) (queue.Handler, error) { return nil, err }
+
+// Name returns the name the output is configured under, which keys its dead
+// letters, spool and metrics. The single output of an instance has no name,
+// it goes by the given default.
+func Name(cfg *config.Config, def string) string {
+	v := struct {
+		Name string `config:"name"`
+	}{}
+	if err := cfg.Unpack(&v); err != nil || v.Name == "" {
+		return def
+	}
+	return v.Name
+}
+
+// Unpack splits a batch published by the node output into one message per
+// event. The messages get IDs derived from the batch ID and their position,
+// so a redelivered batch yields the same IDs. Other messages are returned
+// as they are.
+func Unpack(message *queue.Message) ([]*queue.Message, error) {
+	if !codec.IsBatch(message.Body) {
+		return []*queue.Message{message}, nil
+	}
+
+	bodies, err := codec.Unpack(message.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*queue.Message, len(bodies))
+	for i, body := range bodies {
+		var index [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(index[:], uint64(i))
+		sum := md5.Sum(append(message.ID[:], index[:n]...))
+
+		m := &queue.Message{
+			Body:      body,
+			Timestamp: message.Timestamp,
+			Attempts:  message.Attempts,
+		}
+		hex.Encode(m.ID[:], sum[:len(m.ID)/2])
+		messages[i] = m
+	}
+	return messages, nil
+}
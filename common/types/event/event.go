@@ -11,7 +11,7 @@ type Event struct {
 	Timestamp time.Time
 	Meta      maps.StringIf
 	Fields    maps.StringIf
-	Private   interface{}
+	Private   interface{} `json:"-"` // publisher state, never serialized
 }
 
 var (
@@ -0,0 +1,134 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Date formats times with the Joda patterns used by Elasticsearch and
+// Logstash index names:
+//
+//	yyyy, yy  year          MM, M  month     MMM, MMMM  month name
+//	dd, d     day           DDD    day of year
+//	HH, H     hour          mm, m  minute    ss, s      second
+//	SSS       milliseconds  ww, w  ISO week  xxxx       ISO week year
+//	YYYY, YY  year, as used by Logstash index names
+//
+// Text in single quotes and characters which are no pattern letters are
+// taken literally, two single quotes stand for one.
+type Date struct {
+	elems []dateElem
+}
+
+type dateElem struct {
+	letter byte
+	count  int
+	text   string
+}
+
+func CompileDate(pattern string) (*Date, error) {
+	d := &Date{}
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '\'' && i+1 < len(pattern) && pattern[i+1] == '\'':
+			d.elems = append(d.elems, dateElem{text: "'"})
+			i += 2
+		case c == '\'':
+			var text []byte
+			end := i + 1
+			for ; end < len(pattern); end++ {
+				if pattern[end] != '\'' {
+					text = append(text, pattern[end])
+					continue
+				}
+				if end+1 < len(pattern) && pattern[end+1] == '\'' {
+					text = append(text, '\'')
+					end++
+					continue
+				}
+				break
+			}
+			if end >= len(pattern) {
+				return nil, fmt.Errorf("unclosed quote in date pattern '%s'", pattern)
+			}
+			d.elems = append(d.elems, dateElem{text: string(text)})
+			i = end + 1
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			n := 1
+			for i+n < len(pattern) && pattern[i+n] == c {
+				n++
+			}
+			switch c {
+			case 'y', 'Y', 'M', 'd', 'D', 'H', 'm', 's', 'S', 'w', 'x':
+			default:
+				return nil, fmt.Errorf("unsupported letter '%c' in date pattern '%s'", c, pattern)
+			}
+			d.elems = append(d.elems, dateElem{letter: c, count: n})
+			i += n
+		default:
+			d.elems = append(d.elems, dateElem{text: string(c)})
+			i++
+		}
+	}
+	return d, nil
+}
+
+func (d *Date) Format(t time.Time) string {
+	return string(d.Append(nil, t))
+}
+
+func (d *Date) Append(buf []byte, t time.Time) []byte {
+	for _, e := range d.elems {
+		switch e.letter {
+		case 0:
+			buf = append(buf, e.text...)
+		case 'y', 'Y':
+			if e.count == 2 {
+				buf = appendInt(buf, t.Year()%100, 2)
+			} else {
+				buf = appendInt(buf, t.Year(), e.count)
+			}
+		case 'x':
+			year, _ := t.ISOWeek()
+			if e.count == 2 {
+				year %= 100
+			}
+			buf = appendInt(buf, year, e.count)
+		case 'M':
+			switch {
+			case e.count >= 4:
+				buf = append(buf, t.Month().String()...)
+			case e.count == 3:
+				buf = append(buf, t.Month().String()[:3]...)
+			default:
+				buf = appendInt(buf, int(t.Month()), e.count)
+			}
+		case 'd':
+			buf = appendInt(buf, t.Day(), e.count)
+		case 'D':
+			buf = appendInt(buf, t.YearDay(), e.count)
+		case 'H':
+			buf = appendInt(buf, t.Hour(), e.count)
+		case 'm':
+			buf = appendInt(buf, t.Minute(), e.count)
+		case 's':
+			buf = appendInt(buf, t.Second(), e.count)
+		case 'S':
+			buf = appendInt(buf, t.Nanosecond()/int(time.Millisecond), 3)
+		case 'w':
+			_, week := t.ISOWeek()
+			buf = appendInt(buf, week, e.count)
+		}
+	}
+	return buf
+}
+
+func appendInt(buf []byte, v, width int) []byte {
+	s := strconv.Itoa(v)
+	for n := len(s); n < width; n++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, s...)
+}
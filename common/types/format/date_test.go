@@ -0,0 +1,49 @@
+// +build !integration
+
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDate(t *testing.T) {
+	// a Monday of ISO week 53 of 2020
+	ts := time.Date(2021, time.January, 4, 7, 8, 9, 123456789, time.UTC).AddDate(0, 0, -7)
+
+	tests := []struct {
+		pattern string
+		result  string
+	}{
+		{"yyyy.MM.dd", "2020.12.28"},
+		{"YYYY.MM.dd", "2020.12.28"},
+		{"yy-M-d", "20-12-28"},
+		{"MMM MMMM", "Dec December"},
+		{"DDD", "363"},
+		{"HH:mm:ss.SSS", "07:08:09.123"},
+		{"H:m:s", "7:8:9"},
+		{"xxxx-'w'ww", "2020-w53"},
+		{"yyyy'T'HH", "2020T07"},
+		{"'o''clock' HH", "o'clock 07"},
+		{"HH''mm", "07'08"},
+		{"''", "'"},
+		{"yyyy/MM", "2020/12"},
+	}
+
+	for _, test := range tests {
+		d, err := CompileDate(test.pattern)
+		if !assert.NoError(t, err, test.pattern) {
+			continue
+		}
+		assert.Equal(t, test.result, d.Format(ts), test.pattern)
+	}
+}
+
+func TestDateErrors(t *testing.T) {
+	for _, pattern := range []string{"yyyy 'open", "yyyy.QQ", "''' "} {
+		_, err := CompileDate(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
@@ -0,0 +1,215 @@
+// Package format implements format strings over events:
+//
+//	%{[field]}          value of the event field, nested as [a][b] or [a.b]
+//	%{[field]:default}  default if the event has no such field
+//	%{+yyyy.MM.dd}      event timestamp in UTC, see Date
+//	%%                  a single %
+//
+// Everything else is taken literally.
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+var ErrMissing = errors.New("missing field")
+
+type part interface {
+	run(e *event.Event, buf []byte) ([]byte, error)
+}
+
+type String struct {
+	raw   string
+	parts []part
+	fixed bool
+	text  string // result of fixed strings, with %% unescaped
+}
+
+type literal string
+
+type field struct {
+	key    string
+	def    string
+	hasDef bool
+}
+
+type timestamp struct {
+	date *Date
+}
+
+// Compile parses the format string.
+func Compile(s string) (*String, error) {
+	f := &String{raw: s, fixed: true}
+
+	var text []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 >= len(s) {
+			text = append(text, s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '%':
+			text = append(text, '%')
+			i++
+			continue
+		case '{':
+		default:
+			text = append(text, s[i])
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed %%{ in format string '%s'", s)
+		}
+		expr := s[i+2 : i+end]
+		i += end
+
+		p, err := compileExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%v in format string '%s'", err, s)
+		}
+
+		if len(text) > 0 {
+			f.parts = append(f.parts, literal(text))
+			text = nil
+		}
+		f.parts = append(f.parts, p)
+		f.fixed = false
+	}
+
+	if len(text) > 0 {
+		f.parts = append(f.parts, literal(text))
+	}
+	if f.fixed {
+		f.text = string(text)
+	}
+	return f, nil
+}
+
+// MustCompile is Compile panicking on errors, for format strings known at
+// compile time.
+func MustCompile(s string) *String {
+	f, err := Compile(s)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func compileExpr(expr string) (part, error) {
+	if strings.HasPrefix(expr, "+") {
+		date, err := CompileDate(expr[1:])
+		if err != nil {
+			return nil, err
+		}
+		return timestamp{date: date}, nil
+	}
+
+	if !strings.HasPrefix(expr, "[") {
+		return nil, fmt.Errorf("invalid expression '%%{%s}'", expr)
+	}
+
+	end := strings.LastIndexByte(expr, ']')
+	if end < 0 {
+		return nil, fmt.Errorf("unclosed field '%s'", expr)
+	}
+	var keys []string
+	for _, key := range strings.Split(expr[1:end], "][") {
+		if key == "" || strings.ContainsAny(key, "[]") {
+			return nil, fmt.Errorf("invalid field '%s'", expr[:end+1])
+		}
+		keys = append(keys, key)
+	}
+
+	f := field{key: strings.Join(keys, ".")}
+	if rest := expr[end+1:]; rest != "" {
+		if rest[0] != ':' {
+			return nil, fmt.Errorf("invalid expression '%%{%s}'", expr)
+		}
+		f.def, f.hasDef = rest[1:], true
+	}
+	return f, nil
+}
+
+// Run formats the event. It fails for fields the event has no value for,
+// unless they have a default.
+func (f *String) Run(e *event.Event) (string, error) {
+	if f.fixed {
+		return f.text, nil
+	}
+
+	buf, err := f.Append(nil, e)
+	return string(buf), err
+}
+
+// Append appends the formatted event to buf.
+func (f *String) Append(buf []byte, e *event.Event) ([]byte, error) {
+	var err error
+	for _, p := range f.parts {
+		if buf, err = p.run(e, buf); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// IsConst reports whether the result does not depend on the event.
+func (f *String) IsConst() bool {
+	return f.fixed
+}
+
+func (f *String) String() string {
+	return f.raw
+}
+
+// Unpack lets format strings be used in config structs.
+func (f *String) Unpack(s string) error {
+	compiled, err := Compile(s)
+	if err != nil {
+		return err
+	}
+	*f = *compiled
+	return nil
+}
+
+func (l literal) run(e *event.Event, buf []byte) ([]byte, error) {
+	return append(buf, l...), nil
+}
+
+func (f field) run(e *event.Event, buf []byte) ([]byte, error) {
+	v, err := e.GetValue(f.key)
+	if err != nil || v == nil {
+		if f.hasDef {
+			return append(buf, f.def...), nil
+		}
+		return buf, fmt.Errorf("%v '%s'", ErrMissing, f.key)
+	}
+
+	switch value := v.(type) {
+	case string:
+		return append(buf, value...), nil
+	case time.Time:
+		return value.UTC().AppendFormat(buf, time.RFC3339Nano), nil
+	case maps.StringIf, map[string]interface{}, []interface{}:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return buf, err
+		}
+		return append(buf, b...), nil
+	default:
+		return append(buf, fmt.Sprint(value)...), nil
+	}
+}
+
+func (t timestamp) run(e *event.Event, buf []byte) ([]byte, error) {
+	return t.date.Append(buf, e.Timestamp.UTC()), nil
+}
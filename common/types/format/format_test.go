@@ -0,0 +1,93 @@
+// +build !integration
+
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/types/event"
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		format string
+		fixed  bool
+		text   string
+	}{
+		{"logs", true, "logs"},
+		{"100%% logs%", true, "100% logs%"},
+		{"logs-%{[type]}", false, ""},
+		{"logs-%{+yyyy.MM.dd}", false, ""},
+	}
+
+	for _, test := range tests {
+		f, err := Compile(test.format)
+		if !assert.NoError(t, err, test.format) {
+			continue
+		}
+		assert.Equal(t, test.fixed, f.IsConst(), test.format)
+		assert.Equal(t, test.format, f.String(), test.format)
+		if test.fixed {
+			text, err := f.Run(&event.Event{})
+			assert.NoError(t, err, test.format)
+			assert.Equal(t, test.text, text, test.format)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, format := range []string{
+		"logs-%{[type]",
+		"logs-%{type}",
+		"logs-%{[type}",
+		"logs-%{[a][]}",
+		"logs-%{[type]default}",
+		"logs-%{+yyyy.QQ}",
+	} {
+		_, err := Compile(format)
+		assert.Error(t, err, format)
+	}
+}
+
+func TestRun(t *testing.T) {
+	e := &event.Event{
+		Timestamp: time.Date(2020, time.March, 4, 23, 30, 0, 0, time.FixedZone("CET", 3600)),
+		Fields: maps.StringIf{
+			"type":   "nginx",
+			"count":  3,
+			"host":   maps.StringIf{"name": "web-1"},
+			"labels": maps.StringIf{"env": "prod"},
+		},
+	}
+
+	tests := []struct {
+		format string
+		result string
+	}{
+		{"logs-%{[type]}", "logs-nginx"},
+		{"%{[host][name]}/%{[host.name]}", "web-1/web-1"},
+		{"n%{[count]}", "n3"},
+		{"%{[labels]}", `{"env":"prod"}`},
+		{"%{[missing]:none}-%{[type]:none}", "none-nginx"},
+		{"%{[type]}-%{+YYYY.MM.dd}", "nginx-2020.03.04"},
+		{"%%{[type]}", "%{[type]}"},
+		{"%{[@timestamp]}", "2020-03-04T22:30:00Z"},
+	}
+
+	for _, test := range tests {
+		f, err := Compile(test.format)
+		if !assert.NoError(t, err, test.format) {
+			continue
+		}
+		result, err := f.Run(e)
+		assert.NoError(t, err, test.format)
+		assert.Equal(t, test.result, result, test.format)
+	}
+
+	_, err := MustCompile("logs-%{[missing]}").Run(e)
+	assert.Error(t, err)
+}
@@ -0,0 +1,352 @@
+package deamon
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+const maxInputSize = 1 << 20
+
+var (
+	errMethod       = errors.New("method not allowed")
+	errNoToken      = errors.New("api token not configured, inputs cannot be changed")
+	errUnauthorized = errors.New("missing or invalid bearer token")
+	errNotReady     = errors.New("inputs not started yet")
+	errNoName       = errors.New("input config without name")
+)
+
+type apiConfig struct {
+	Enabled bool   `config:"enabled"`
+	Host    string `config:"host"`
+	Port    int    `config:"port" validate:"min=0,max=65535"`
+	Socket  string `config:"socket"`
+	Token   string `config:"token"`
+}
+
+var defaultAPIConfig = apiConfig{
+	Host: "localhost",
+	Port: 5066,
+}
+
+// Validate keeps the API local, it must not be reachable from other hosts.
+func (c *apiConfig) Validate() error {
+	if c.Socket != "" {
+		return nil
+	}
+
+	if c.Host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(c.Host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("api host %s is not a loopback address, use localhost or a unix socket", c.Host)
+}
+
+// api serves the management endpoints of the agent. Inputs can only be
+// changed with the token of the config.
+type api struct {
+	token     string
+	registrar *Registrar
+	input     *Input
+	ready     int32
+	listener  net.Listener
+	server    *http.Server
+}
+
+func newAPI(config apiConfig, registrar *Registrar, input *Input) (*api, error) {
+	a := &api{
+		token:     config.Token,
+		registrar: registrar,
+		input:     input,
+	}
+
+	var err error
+	if config.Socket != "" {
+		// a socket left by an earlier run is replaced, other files are not
+		if info, statErr := os.Lstat(config.Socket); statErr == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(config.Socket)
+		}
+		a.listener, err = net.Listen("unix", config.Socket)
+		if err == nil {
+			if err = os.Chmod(config.Socket, 0600); err != nil {
+				a.listener.Close()
+			}
+		}
+	} else {
+		a.listener, err = net.Listen("tcp", net.JoinHostPort(config.Host, fmt.Sprint(config.Port)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start api: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.get(a.healthz))
+	mux.HandleFunc("/readyz", a.get(a.readyz))
+	mux.HandleFunc("/inputs", a.inputs)
+	mux.HandleFunc("/inputs/", a.namedInput)
+	mux.HandleFunc("/states", a.get(a.states))
+	mux.HandleFunc("/outputs", a.get(a.outputs))
+	a.server = &http.Server{Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+
+	go func() {
+		if err := a.server.Serve(a.listener); err != nil && err != http.ErrServerClosed {
+			log.Err("API stopped: %v", err)
+		}
+	}()
+
+	log.Info("API listening on %s", a.listener.Addr())
+	return a, nil
+}
+
+// setReady marks whether the inputs are running.
+func (a *api) setReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&a.ready, v)
+}
+
+func (a *api) stop() {
+	a.setReady(false)
+	a.server.Close()
+}
+
+func (a *api) get(handler func(*http.Request) (int, interface{})) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, errorBody(errMethod))
+			return
+		}
+
+		status, body := handler(r)
+		writeJSON(w, status, body)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func (a *api) healthz(r *http.Request) (int, interface{}) {
+	return http.StatusOK, map[string]string{"status": "ok"}
+}
+
+func (a *api) readyz(r *http.Request) (int, interface{}) {
+	if atomic.LoadInt32(&a.ready) == 0 {
+		return http.StatusServiceUnavailable, map[string]string{"status": "not ready"}
+	}
+	return http.StatusOK, map[string]string{"status": "ready"}
+}
+
+// inputs lists the inputs, or creates one from the config posted.
+func (a *api) inputs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, a.input.status())
+	case http.MethodPost:
+		a.write(w, r, func(reload *Reload) (int, interface{}) {
+			c, name, err := readInput(r, "")
+			if err != nil {
+				return http.StatusBadRequest, errorBody(err)
+			}
+			created, err := reload.CreateInput(name, c)
+			if err != nil {
+				return inputError(err)
+			}
+			if !created {
+				return http.StatusConflict, errorBody(fmt.Errorf("input %s exists already", name))
+			}
+			return http.StatusCreated, map[string]string{"name": name, "status": "created"}
+		})
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody(errMethod))
+	}
+}
+
+// namedInput creates or replaces the input of the name with the config put, or
+// deletes it.
+func (a *api) namedInput(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/inputs/")
+	if name == "" || strings.Contains(name, "/") {
+		writeJSON(w, http.StatusNotFound, errorBody(fmt.Errorf("no input %q", name)))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		a.write(w, r, func(reload *Reload) (int, interface{}) {
+			c, _, err := readInput(r, name)
+			if err != nil {
+				return http.StatusBadRequest, errorBody(err)
+			}
+			return putInput(reload, name, c)
+		})
+	case http.MethodDelete:
+		a.write(w, r, func(reload *Reload) (int, interface{}) {
+			deleted, err := reload.DeleteInput(name)
+			if err != nil {
+				return http.StatusInternalServerError, errorBody(err)
+			}
+			if !deleted {
+				return http.StatusNotFound, errorBody(fmt.Errorf("no input %s was created through the api", name))
+			}
+			return http.StatusOK, map[string]string{"name": name, "status": "deleted"}
+		})
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody(errMethod))
+	}
+}
+
+// write runs the handler if the request carries the token of the api, and
+// the inputs are running.
+func (a *api) write(w http.ResponseWriter, r *http.Request, handler func(*Reload) (int, interface{})) {
+	if a.token == "" {
+		writeJSON(w, http.StatusForbidden, errorBody(errNoToken))
+		return
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(a.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scribe"`)
+		writeJSON(w, http.StatusUnauthorized, errorBody(errUnauthorized))
+		return
+	}
+
+	reload := a.input.reloader()
+	if reload == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorBody(errNotReady))
+		return
+	}
+
+	status, body := handler(reload)
+	writeJSON(w, status, body)
+}
+
+// readInput reads the config of an input from the request body, in YAML or
+// JSON. The name of the config must match the name given, it is set if
+// missing.
+func readInput(r *http.Request, name string) (*config.Config, string, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxInputSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxInputSize {
+		return nil, "", fmt.Errorf("input config exceeds %d bytes", maxInputSize)
+	}
+
+	c, err := config.LoadStream(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid input config: %v", err)
+	}
+
+	var input struct {
+		Name string `config:"name"`
+	}
+	if err := c.Unpack(&input); err != nil {
+		return nil, "", fmt.Errorf("invalid input config: %v", err)
+	}
+
+	switch {
+	case input.Name == "" && name == "":
+		return nil, "", errNoName
+	case input.Name == "":
+		if err := c.SetString("name", -1, name); err != nil {
+			return nil, "", err
+		}
+	case name != "" && input.Name != name:
+		return nil, "", fmt.Errorf("input config is named %s, not %s", input.Name, name)
+	default:
+		name = input.Name
+	}
+	return c, name, nil
+}
+
+func putInput(reload *Reload, name string, c *config.Config) (int, interface{}) {
+	replaced, err := reload.PutInput(name, c)
+	if err != nil {
+		return inputError(err)
+	}
+
+	if replaced {
+		return http.StatusOK, map[string]string{"name": name, "status": "replaced"}
+	}
+	return http.StatusCreated, map[string]string{"name": name, "status": "created"}
+}
+
+// inputError answers configs which are invalid with a bad request.
+func inputError(err error) (int, interface{}) {
+	if _, ok := err.(validationError); ok {
+		return http.StatusBadRequest, errorBody(err)
+	}
+	return http.StatusInternalServerError, errorBody(err)
+}
+
+func errorBody(err error) map[string]string {
+	return map[string]string{"error": err.Error()}
+}
+
+func (a *api) states(r *http.Request) (int, interface{}) {
+	type state struct {
+		State
+		Finished bool `json:"finished"`
+	}
+
+	states := a.registrar.GetStates()
+	result := make([]state, len(states))
+	for i, s := range states {
+		result[i] = state{State: s, Finished: s.Finished}
+	}
+	return http.StatusOK, result
+}
+
+// outputs reports the event counters of every output by its name.
+func (a *api) outputs(r *http.Request) (int, interface{}) {
+	outputs := map[string]map[string]interface{}{}
+	for _, m := range metric.Default.Metrics() {
+		d := m.Desc()
+		name, ok := d.Labels["output"]
+		if !ok || len(d.Labels) != 1 || !strings.HasPrefix(d.Name, "output_events_") {
+			continue
+		}
+
+		c, ok := m.(*metric.Counter)
+		if !ok {
+			continue
+		}
+
+		if outputs[name] == nil {
+			outputs[name] = map[string]interface{}{}
+		}
+		key := strings.TrimSuffix(strings.TrimPrefix(d.Name, "output_events_"), "_total")
+		outputs[name][key] = c.Get()
+	}
+	return http.StatusOK, outputs
+}
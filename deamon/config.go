@@ -17,6 +17,11 @@ type Config struct {
 	Registry  *registryConfig
 
 	Inputs    []*config.Config
+	API        apiConfig `config:"api"`
+
+	// bounds the wait for the outputs to publish the events read from
+	// stdin, 0 waits until all of them were acked
+	ShutdownTimeout time.Duration `config:"shutdown_timeout"`
 }
 
 type regionConfig struct {
@@ -29,7 +34,10 @@ type registryConfig struct {
 }
 
 var (
-	DefaultConfig = Config{}
+	DefaultConfig = Config{
+		API: defaultAPIConfig,
+		ShutdownTimeout: 30 * time.Second,
+	}
 )
 
 var (
@@ -45,6 +53,7 @@ var (
 type InputConfig struct {
 	Scan  Scan
 	Type  string
+	Name  string
 }
 
 type Scan struct {
@@ -37,13 +37,16 @@ func (s *Scribe) Run(sentry *component.Sentry) error {
 	waitFinished := newSignalWait()
 	waitEvents := newSignalWait()
 
-	registrar, err := newRegistrar(config.Registry.File, config.Registry.Flush, nil)
+	// counts the events in flight, until their states were written
+	events := newEventCounter()
+
+	registrar, err := newRegistrar(config.Registry.File, config.Registry.Flush, events)
 	if err != nil {
 		log.Err("Could not init registrar: %v", err)
 		return err
 	}
 
-	input, err := newInput(sentry.Info, sentry.Handler, s.config.Inputs, s.done)
+	input, err := newInput(sentry.Info, sentry.Handler, s.config.Inputs, events, s.done)
 	if err != nil {
 		return err
 	}
@@ -54,6 +57,15 @@ func (s *Scribe) Run(sentry *component.Sentry) error {
 	}
 	defer registrar.Stop()
 
+	var server *api
+	if config.API.Enabled {
+		server, err = newAPI(config.API, registrar, input)
+		if err != nil {
+			return err
+		}
+		defer server.stop()
+	}
+
 	defer waitEvents.Wait()
 
 	err = input.start(registrar, config)
@@ -62,10 +74,32 @@ func (s *Scribe) Run(sentry *component.Sentry) error {
 		return err
 	}
 
+	if server != nil {
+		server.setReady(true)
+	}
+
 	waitFinished.AddChan(s.done)
+	if input.once {
+		log.Info("Reading from stdin. Waiting for completion ...")
+		waitFinished.Add(withLog(input.wait, "All data collection completed. Shutting down."))
+	}
 	waitFinished.Wait()
 
+	if server != nil {
+		server.setReady(false)
+	}
 	input.stop()
+
+	if input.once {
+		timeout := config.ShutdownTimeout
+		waitEvents.Add(func() {
+			if events.Wait(timeout) {
+				log.Info("Continue shutdown: All enqueued events being published.")
+			} else {
+				log.Warn("Continue shutdown: Timed out after %v waiting for the enqueued events to be published.", timeout)
+			}
+		})
+	}
 	return nil
 }
 
@@ -10,13 +10,18 @@ cfg	"github.com/queueio/sentry/utils/config"
 
 type Context struct {
 	States     []State
+	Outlet    *Outlet
 	Done       chan struct{}
 	SentryDone chan struct{}
 }
 
 type Factory func(config *cfg.Config, handler queue.Handler, context Context) (Collector, error)
 
+// Validator checks the config of a collector without creating it.
+type Validator func(config *cfg.Config) error
+
 var factories = make(map[string]Factory)
+var validators = make(map[string]Validator)
 
 func Register(name string, factory Factory) error {
 	log.Info("Register collector factory")
@@ -42,3 +47,39 @@ func Load(name string) (Factory, error) {
 	}
 	return factories[name], nil
 }
+
+
+// RegisterValidator registers the config check of a collector type, which is
+// used for configs received at runtime.
+func RegisterValidator(name string, validator Validator) error {
+	if name == "" {
+		return fmt.Errorf("Error register validator: name cannot be empty")
+	}
+	if validator == nil {
+		return fmt.Errorf("Error register validator '%v': validator cannot be empty", name)
+	}
+	if _, exists := validators[name]; exists {
+		return fmt.Errorf("Error register validator '%v': already registered", name)
+	}
+
+	validators[name] = validator
+	return nil
+}
+
+// Validate checks the config of an input by the validator of its type,
+// without side effects.
+func Validate(config *cfg.Config) error {
+	input := defaultConfig
+	if err := config.Unpack(&input); err != nil {
+		return err
+	}
+
+	if _, err := Load(input.Type); err != nil {
+		return err
+	}
+
+	if validator, ok := validators[input.Type]; ok {
+		return validator(config)
+	}
+	return nil
+}
\ No newline at end of file
@@ -2,6 +2,7 @@ package deamon
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/queueio/sentry/utils/log"
@@ -12,6 +13,7 @@ import (
 )
 
 type Input struct {
+	sync.RWMutex
 	robots      map[uint64]*Robot
 	configs     []*config.Config
 	wg          sync.WaitGroup
@@ -20,12 +22,15 @@ type Input struct {
 	sentryDone  chan struct{}
 	output      queue.Handler
 	info        component.Info
+	events     *eventCounter
+	once        bool
 }
 
 func newInput(
 	info component.Info,
 	output queue.Handler,
 	inputs []*config.Config,
+	events *eventCounter,
 	done chan struct{}) (*Input, error) {
 	return &Input{
 		robots: map[uint64]*Robot{},
@@ -33,6 +38,7 @@ func newInput(
 		done:       done,
 		output:  output,
 		info: info,
+		events: events,
 	}, nil
 }
 
@@ -44,7 +50,7 @@ func (i *Input) start(registrar *Registrar, config *Config) error {
 			return nil
 		}
 
-		r, err := NewRobot(config, i.output, i.sentryDone, registrar.GetStates())
+		r, err := NewRobot(config, i.output, i.sentryDone, registrar, i.events)
 		if err != nil {
 			return fmt.Errorf("Error in initing robot: %s", err)
 		}
@@ -53,29 +59,90 @@ func (i *Input) start(registrar *Registrar, config *Config) error {
 			return fmt.Errorf("Robot with same ID already exists: %d", r.ID)
 		}
 
+		i.Lock()
 		i.robots[r.ID] = r
+		i.Unlock()
+
+		if r.config.Type == StdinType {
+			i.once = true
+		}
 
 		r.Start()
 	}
 
-	var err error
-
-	if i.reload, err = newReload(config, i.info); err != nil {
+	reload, err := newReload(config, i.info)
+	if err != nil {
 		return err
 	}
 
-	runner := newRunner(i.output, registrar, i.sentryDone)
+	runner := newRunner(i.output, registrar, i.events, i.sentryDone)
+	reload.runner = runner
+
+	i.Lock()
+	i.reload = reload
+	i.Unlock()
+
 	go func() {
-		i.reload.Run(runner)
+		reload.Run(runner)
 	}()
 
 	return nil
 }
 
+// reloader returns the reload of the input once it was started.
+func (i *Input) reloader() *Reload {
+	i.RLock()
+	defer i.RUnlock()
+
+	return i.reload
+}
+
+// status describes the robots started from the config and by reloads,
+// sorted by their ID.
+func (i *Input) status() []RobotStatus {
+	i.RLock()
+	robots := make([]*Robot, 0, len(i.robots))
+	for _, robot := range i.robots {
+		robots = append(robots, robot)
+	}
+	reload := i.reload
+	i.RUnlock()
+
+	if reload != nil {
+		for _, runner := range reload.module.CopyList() {
+			if robot, ok := runner.(*Robot); ok {
+				robots = append(robots, robot)
+			}
+		}
+	}
+
+	status := make([]RobotStatus, len(robots))
+	for n, robot := range robots {
+		status[n] = robot.Status()
+	}
+	sort.Slice(status, func(a, b int) bool { return status[a].ID < status[b].ID })
+	return status
+}
+
+// wait blocks until all stdin robots finished reading.
+func (i *Input) wait() {
+	for _, robot := range i.robots {
+		if robot.config.Type == StdinType {
+			robot.Wait()
+		}
+	}
+}
+
 func (i *Input) stop() error {
 	for id, robot := range i.robots {
 		robot.Stop()
 		log.Info("%d number robot stop", id)
 	}
+
+	// the robots of reloads and the api hand their final states to the
+	// registrar before it stops
+	if reload := i.reloader(); reload != nil {
+		reload.stopModules()
+	}
 	return nil
 }
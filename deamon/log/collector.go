@@ -13,8 +13,9 @@ import (
 cfg	"github.com/queueio/sentry/utils/config"
 
 	"github.com/queueio/sentry/components/scribe"
+	"github.com/queueio/sentry/components/scribe/metric"
 	"github.com/queueio/sentry/utils/queue"
-	"github.com/queueio/sentry/utils/outputs"
+	"github.com/queueio/sentry/utils/outputs/route"
 )
 
 const (
@@ -26,14 +27,30 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	err = scribe.RegisterValidator("log", Validate)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Validate checks the config of a log input without creating its collector.
+func Validate(cfg *cfg.Config) error {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return err
+	}
+	return config.Validate()
 }
 
 type Collector struct {
 	cfg       *cfg.Config
 	config     config
 	states    *scribe.States
+	outlet    *scribe.Outlet
 	executor  *job.Executor
 	output     queue.Handler
+	metrics   *metric.Input
 	done       chan struct{}
 }
 
@@ -44,12 +61,14 @@ func New(cfg *cfg.Config, handler queue.Handler, context scribe.Context) (scribe
 		executor:    job.New(),
 		output:    handler,
 		states:      &scribe.States{},
+		outlet:      context.Outlet,
 		done:        context.Done,
 	}
 
 	if err := cfg.Unpack(&c.config); err != nil {
 		return nil, err
 	}
+	c.metrics = metric.NewInput(c.config.Name)
 	if err := c.config.resolvePaths(); err != nil {
 		log.Err("Failed to resolve paths in config: %+v", err)
 		return nil, err
@@ -109,6 +128,7 @@ func (c *Collector) Run() {
 	}
 
 	c.visitor()
+	c.metrics.Scanners.Set(int64(c.executor.Len()))
 
 	if c.config.State.Clean.Inactive > 0 || c.config.State.Clean.Removed {
 		beforeCount := c.states.Count()
@@ -305,6 +325,7 @@ func (c *Collector) visitor() {
 	var files []string
 
 	paths := c.getFiles()
+	c.metrics.Files.Set(int64(len(paths)))
 
 	var err error
 
@@ -469,11 +490,16 @@ func (c *Collector) isCleanInactive(state scribe.State) bool {
 }
 
 func (c *Collector) createScanner(state scribe.State) (*Scanner, error) {
-	output := outputs.GroupPublish(c.config.Name, c.output)
+	output, err := route.Publisher(c.config.Name, c.output, c.config.Outputs)
+	if err != nil {
+		return nil, err
+	}
+
 	return NewScanner(
 		c.cfg,
 		state,
 		c.states,
+		c.outlet,
 		output,
 	)
 }
@@ -507,12 +533,23 @@ func (c *Collector) updateState(state scribe.State) error {
 	}
 
 	c.states.Update(state)
-
-	data := scribe.NewData()
-	data.SetState(state)
+	c.outlet.Update(state)
 	return nil
 }
 
+// Scanners describes the files the collector is reading.
+func (c *Collector) Scanners() []scribe.ScannerStatus {
+	var scanners []scribe.ScannerStatus
+	for _, task := range c.executor.Tasks() {
+		if s, ok := task.(*Scanner); ok {
+			scanners = append(scanners, s.Status())
+		}
+	}
+
+	sort.Slice(scanners, func(i, j int) bool { return scanners[i].Source < scanners[j].Source })
+	return scanners
+}
+
 func (c *Collector) Wait() {
 	c.executor.WaitForCompletion()
 	c.Stop()
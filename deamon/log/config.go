@@ -8,6 +8,7 @@ import (
 	"github.com/elastic/beats/libbeat/common/match"
 
 	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/utils/outputs/route"
 	"github.com/queueio/sentry/components/scribe/log/reader"
 	"github.com/queueio/sentry/components/scribe"
 )
@@ -80,8 +81,11 @@ type config struct {
 	Include      Include
 
 	Max          Max
+	Framing      *reader.FramingConfig   `config:"framing"`
 	Multiline    *reader.MultilineConfig `config:"multiline"`
 	JSON         *reader.JSONConfig      `config:"json"`
+	Timestamp    *reader.TimestampConfig `config:"timestamp"`
+	Outputs      []route.Config          `config:"outputs"`
 }
 
 type Max struct {
@@ -201,6 +205,18 @@ func (c *config) Validate() error {
 		return fmt.Errorf("When using the JSON decoder and line filtering together, you need to specify a message_key value")
 	}
 
+	if c.Timestamp != nil && c.Timestamp.Field != "" && c.JSON == nil {
+		return fmt.Errorf("When taking the timestamp from a field, you need to enable the JSON decoder")
+	}
+
+	if c.Framing != nil && c.Framing.Type == reader.FramingLengthPrefixed && c.Max.Bytes <= 0 {
+		return fmt.Errorf("length_prefixed framing requires max_bytes greater than 0")
+	}
+
+	if _, err := reader.FindEncoding(c.Encoding); err != nil {
+		return err
+	}
+
 	if c.Scanner.Sort != "" {
 		log.Warn("scan_sort is used.")
 
@@ -8,3 +8,16 @@ type File struct {
 
 func (File) Continuable() bool { return true }
 func (File) HasState() bool    { return true }
+
+// Pipe wraps a non seekable stream such as stdin. It is read until EOF and
+// has no state which could be persisted in the registry.
+type Pipe struct {
+	File *os.File
+}
+
+func (p Pipe) Read(b []byte) (int, error) { return p.File.Read(b) }
+func (p Pipe) Close() error               { return p.File.Close() }
+func (p Pipe) Name() string               { return p.File.Name() }
+func (p Pipe) Stat() (os.FileInfo, error) { return p.File.Stat() }
+func (p Pipe) Continuable() bool          { return false }
+func (p Pipe) HasState() bool             { return false }
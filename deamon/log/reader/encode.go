@@ -1,24 +1,37 @@
 package reader
 
 import (
-	"io"
 	"time"
+
+	"golang.org/x/text/encoding"
 )
 
+// Encode reads the records of the framer and decodes them to UTF-8, Bytes
+// counts the raw bytes read.
 type Encode struct {
-	reader *Line
+	reader  Framer
+	decoder *encoding.Decoder
 }
 
-func NewEncode(reader io.Reader) (Encode, error) {
-	r, err := NewLine(reader)
-	return Encode{r}, err
+func NewEncode(reader Framer, enc Encoding) (Encode, error) {
+	return Encode{reader, enc.decoder()}, nil
 }
 
 func (e Encode) Next() (Message, error) {
-	c, sz, err := e.reader.Next()
-	return Message{
+	c, sz, truncated, err := e.reader.Next()
+	if err == nil {
+		linesRead.Inc()
+		if e.decoder != nil && len(c) > 0 {
+			c, err = e.decoder.Bytes(c)
+		}
+	}
+	message := Message{
 		Ts:      time.Now(),
 		Content: c,
 		Bytes:   sz,
-	}, err
+	}
+	if truncated {
+		message.markTruncated()
+	}
+	return message, err
 }
\ No newline at end of file
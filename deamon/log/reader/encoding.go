@@ -0,0 +1,147 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Encoding describes the character set of a file. Lines are split on the
+// newline as encoded in the file and decoded to UTF-8 afterwards, so the
+// number of raw bytes of every line is known and offsets stay in file bytes.
+type Encoding struct {
+	Name     string
+	encoding encoding.Encoding // nil for content passed through as is
+	newline  []byte
+}
+
+var (
+	utf16LE = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	utf16BE = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+)
+
+var encodings = map[string]encoding.Encoding{
+	"plain":        nil,
+	"utf-8":        unicode.UTF8,
+	"utf-16le":     utf16LE,
+	"utf-16be":     utf16BE,
+	"gbk":          simplifiedchinese.GBK,
+	"gb18030":      simplifiedchinese.GB18030,
+	"big5":         traditionalchinese.Big5,
+	"shift-jis":    japanese.ShiftJIS,
+	"euc-jp":       japanese.EUCJP,
+	"euc-kr":       korean.EUCKR,
+	"iso8859-1":    charmap.ISO8859_1,
+	"iso8859-2":    charmap.ISO8859_2,
+	"iso8859-15":   charmap.ISO8859_15,
+	"windows-1250": charmap.Windows1250,
+	"windows-1251": charmap.Windows1251,
+	"windows-1252": charmap.Windows1252,
+	"koi8-r":       charmap.KOI8R,
+}
+
+var encodingAliases = map[string]string{
+	"":           "plain",
+	"utf8":       "utf-8",
+	"utf16le":    "utf-16le",
+	"utf16be":    "utf-16be",
+	"sjis":       "shift-jis",
+	"shiftjis":   "shift-jis",
+	"latin1":     "iso8859-1",
+	"latin-1":    "iso8859-1",
+	"iso-8859-1": "iso8859-1",
+	"cp1252":     "windows-1252",
+}
+
+// Plain passes the content through without decoding.
+var Plain, _ = FindEncoding("plain")
+
+// FindEncoding returns the encoding of the name, case and underscores
+// ignored.
+func FindEncoding(name string) (Encoding, error) {
+	key := strings.Replace(strings.ToLower(strings.TrimSpace(name)), "_", "-", -1)
+	if alias, ok := encodingAliases[key]; ok {
+		key = alias
+	}
+
+	enc, ok := encodings[key]
+	if !ok {
+		return Encoding{}, fmt.Errorf("unknown encoding %q", name)
+	}
+	return newEncoding(key, enc), nil
+}
+
+func newEncoding(name string, enc encoding.Encoding) Encoding {
+	e := Encoding{Name: name, encoding: enc, newline: []byte{'\n'}}
+	if enc != nil {
+		// Every supported encoding shares the ASCII newline, except for the
+		// width of UTF-16.
+		e.newline, _ = enc.NewEncoder().Bytes(e.newline)
+	}
+	return e
+}
+
+// DetectBOM returns the encoding announced by the byte order mark the head
+// of a file starts with. The BOM itself is kept in the content, it is decoded
+// to U+FEFF and trimmed by the scanner.
+func DetectBOM(head []byte) (Encoding, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return newEncoding("utf-8", unicode.UTF8), true
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return newEncoding("utf-16le", utf16LE), true
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return newEncoding("utf-16be", utf16BE), true
+	}
+	return Encoding{}, false
+}
+
+// decoder returns a new decoder of the encoding, nil if the content is not
+// decoded.
+func (e Encoding) decoder() *encoding.Decoder {
+	if e.encoding == nil {
+		return nil
+	}
+	return e.encoding.NewDecoder()
+}
+
+// encode returns the text as encoded in the file.
+func (e Encoding) encode(text string) ([]byte, error) {
+	if e.encoding == nil {
+		return []byte(text), nil
+	}
+	return e.encoding.NewEncoder().Bytes([]byte(text))
+}
+
+// index returns the length of the first record of the data including the
+// delimiter, -1 if the data holds no delimiter. The delimiter is only matched
+// at code unit boundaries.
+func (e Encoding) index(data, delimiter []byte) int {
+	width := e.width()
+	for from := 0; from < len(data); {
+		i := bytes.Index(data[from:], delimiter)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if i%width != 0 {
+			from = i + 1
+			continue
+		}
+		return i + len(delimiter)
+	}
+	return -1
+}
+
+// width returns the number of bytes of a code unit of the encoding.
+func (e Encoding) width() int {
+	return len(e.newline)
+}
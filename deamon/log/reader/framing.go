@@ -0,0 +1,128 @@
+package reader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Framer reads the raw records of a source. Next returns a record, the
+// number of bytes it consumed and whether it was cut at max_bytes.
+type Framer interface {
+	Next() ([]byte, int, bool, error)
+}
+
+// NewFramer returns the framer of the config, lines if it is nil.
+func NewFramer(input io.Reader, encoding Encoding, config *FramingConfig, bufferSize, maxBytes int) (Framer, error) {
+	if config.IsNewline() {
+		return NewLine(input, encoding, bufferSize, maxBytes)
+	}
+
+	switch config.Type {
+	case FramingDelimiter:
+		return NewDelimiter(input, encoding, config.Delimiter, bufferSize, maxBytes)
+	case FramingNUL:
+		return NewDelimiter(input, encoding, "\x00", bufferSize, maxBytes)
+	case FramingLengthPrefixed:
+		return NewLengthPrefixed(input, bufferSize, maxBytes), nil
+	case FramingFixed:
+		return NewFixed(input, config.Size, bufferSize, maxBytes), nil
+	}
+	return nil, fmt.Errorf("unknown framing type: %s", config.Type)
+}
+
+// LengthPrefixed reads records preceded by their length as 4 byte big endian
+// integer. Records longer than maxBytes are cut, the rest is skipped. The
+// length is read from the data, so maxBytes bounds the memory of a record.
+type LengthPrefixed struct {
+	reader   io.Reader
+	maxBytes int
+	chunk    []byte
+}
+
+func NewLengthPrefixed(input io.Reader, bufferSize, maxBytes int) *LengthPrefixed {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &LengthPrefixed{reader: input, maxBytes: maxBytes, chunk: make([]byte, bufferSize)}
+}
+
+// Next returns the next record. Records incomplete at the end of the input
+// are not returned, they are read again from their start.
+func (l *LengthPrefixed) Next() ([]byte, int, bool, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(l.reader, prefix[:]); err != nil {
+		return nil, 0, false, frameError(err)
+	}
+
+	length := int(binary.BigEndian.Uint32(prefix[:]))
+	if length < 0 {
+		return nil, 0, false, fmt.Errorf("record length %d exceeds the int range", binary.BigEndian.Uint32(prefix[:]))
+	}
+	record, truncated, err := readRecord(l.reader, length, l.maxBytes, l.chunk)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return record, len(prefix) + length, truncated, nil
+}
+
+// Fixed reads records of the same size. Records longer than maxBytes are cut,
+// the rest is skipped.
+type Fixed struct {
+	reader   io.Reader
+	size     int
+	maxBytes int
+	chunk    []byte
+}
+
+func NewFixed(input io.Reader, size, bufferSize, maxBytes int) *Fixed {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &Fixed{reader: input, size: size, maxBytes: maxBytes, chunk: make([]byte, bufferSize)}
+}
+
+// Next returns the next record. Records incomplete at the end of the input
+// are not returned, they are read again from their start.
+func (f *Fixed) Next() ([]byte, int, bool, error) {
+	record, truncated, err := readRecord(f.reader, f.size, f.maxBytes, f.chunk)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return record, f.size, truncated, nil
+}
+
+// readRecord reads a record of the length, keeping at most maxBytes of it.
+// The rest is read in chunks and dropped.
+func readRecord(r io.Reader, length, maxBytes int, chunk []byte) ([]byte, bool, error) {
+	keep := length
+	if maxBytes > 0 && keep > maxBytes {
+		keep = maxBytes
+	}
+
+	record := make([]byte, keep)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, false, frameError(err)
+	}
+
+	for skip := length - keep; skip > 0; {
+		n := skip
+		if n > len(chunk) {
+			n = len(chunk)
+		}
+		if _, err := io.ReadFull(r, chunk[:n]); err != nil {
+			return nil, false, frameError(err)
+		}
+		skip -= n
+	}
+	return record, keep < length, nil
+}
+
+// frameError reports a record cut by the end of the input as the end of the
+// input.
+func frameError(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return err
+}
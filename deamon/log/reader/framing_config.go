@@ -0,0 +1,41 @@
+package reader
+
+import (
+	"fmt"
+)
+
+const (
+	FramingNewline        = "newline"
+	FramingDelimiter      = "delimiter"
+	FramingNUL            = "nul"
+	FramingLengthPrefixed = "length_prefixed"
+	FramingFixed          = "fixed"
+)
+
+type FramingConfig struct {
+	Type      string `config:"type"`
+	Delimiter string `config:"delimiter"`
+	Size      int    `config:"size" validate:"min=0"`
+}
+
+func (c *FramingConfig) Validate() error {
+	switch c.Type {
+	case "", FramingNewline, FramingNUL, FramingLengthPrefixed:
+	case FramingDelimiter:
+		if c.Delimiter == "" {
+			return fmt.Errorf("framing delimiter requires a delimiter")
+		}
+	case FramingFixed:
+		if c.Size <= 0 {
+			return fmt.Errorf("framing fixed requires a size")
+		}
+	default:
+		return fmt.Errorf("unknown framing type: %s", c.Type)
+	}
+	return nil
+}
+
+// IsNewline tells whether the records are lines, which is the default.
+func (c *FramingConfig) IsNewline() bool {
+	return c == nil || c.Type == "" || c.Type == FramingNewline
+}
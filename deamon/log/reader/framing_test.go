@@ -0,0 +1,128 @@
+// +build !integration
+
+package reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLengthPrefixed(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxBytes int
+		records  []record
+	}{
+		{
+			name:  "records",
+			input: "\x00\x00\x00\x03abc\x00\x00\x00\x00\x00\x00\x00\x01d",
+			records: []record{
+				{"abc", 7, false},
+				{"", 4, false},
+				{"d", 5, false},
+			},
+		},
+		{
+			name:     "truncated",
+			input:    "\x00\x00\x00\x06abcdef\x00\x00\x00\x02gh",
+			maxBytes: 4,
+			records: []record{
+				{"abcd", 10, true},
+				{"gh", 6, false},
+			},
+		},
+		{
+			name:    "record cut at eof",
+			input:   "\x00\x00\x00\x02ab\x00\x00\x00\x05cd",
+			records: []record{{"ab", 6, false}},
+		},
+		{
+			name:     "truncated record cut at eof",
+			input:    "\x00\x00\x00\x02ab\x00\x00\x00\x08cdefg",
+			maxBytes: 2,
+			records:  []record{{"ab", 6, false}},
+		},
+		{
+			name:    "prefix cut at eof",
+			input:   "\x00\x00\x00\x01a\x00\x00",
+			records: []record{{"a", 5, false}},
+		},
+	}
+
+	for _, test := range tests {
+		l := NewLengthPrefixed(strings.NewReader(test.input), 2, test.maxBytes)
+		records, err := readAll(t, l)
+		assert.Equal(t, io.EOF, err, test.name)
+		assert.Equal(t, test.records, records, test.name)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		size     int
+		maxBytes int
+		records  []record
+	}{
+		{
+			name:  "records",
+			input: "abcdef",
+			size:  3,
+			records: []record{
+				{"abc", 3, false},
+				{"def", 3, false},
+			},
+		},
+		{
+			name:     "truncated",
+			input:    "abcdefgh",
+			size:     4,
+			maxBytes: 3,
+			records: []record{
+				{"abc", 4, true},
+				{"efg", 4, true},
+			},
+		},
+		{
+			name:    "record cut at eof",
+			input:   "abcdefg",
+			size:    3,
+			records: []record{{"abc", 3, false}, {"def", 3, false}},
+		},
+		{
+			name:     "truncated record cut at eof",
+			input:    "abcdefg",
+			size:     5,
+			maxBytes: 2,
+			records:  []record{{"ab", 5, true}},
+		},
+	}
+
+	for _, test := range tests {
+		f := NewFixed(strings.NewReader(test.input), test.size, 2, test.maxBytes)
+		records, err := readAll(t, f)
+		assert.Equal(t, io.EOF, err, test.name)
+		assert.Equal(t, test.records, records, test.name)
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	l, err := NewDelimiter(strings.NewReader("a||b|c||||d"), Plain, "||", 2, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	records, err := readAll(t, l)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []record{
+		{"a", 3, false},
+		{"b|c", 5, false},
+		{"", 2, false},
+		{"d", 1, false},
+	}, records)
+}
@@ -27,6 +27,7 @@ func (r *JSON) decodeJSON(text []byte) ([]byte, maps.StringIf) {
 	err := unmarshal(text, &jsonFields)
 	if err != nil || jsonFields == nil {
 		log.Err("Error decoding JSON: %v", err)
+		jsonErrors.Inc()
 		if r.cfg.AddErrorKey {
 			jsonFields = maps.StringIf{"error": createJSONError(fmt.Sprintf("Error decoding JSON: %v", err))}
 		}
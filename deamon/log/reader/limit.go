@@ -13,6 +13,7 @@ func (p *Limit) Next() (Message, error) {
 	message, err := p.reader.Next()
 	if len(message.Content) > p.maxBytes {
 		message.Content = message.Content[:p.maxBytes]
+		message.markTruncated()
 	}
 	return message, err
 }
\ No newline at end of file
@@ -1,24 +1,157 @@
 package reader
 
 import (
-	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 )
 
+const defaultBufferSize = 16 * 1024
+
+// Line reads the raw records of an encoding ending in a delimiter, the
+// newline by default. Records are read in chunks of the buffer size, so their
+// length is not bounded by the buffer. Records whose content, the line ending
+// left out, is longer than maxBytes are cut without their line ending, the
+// rest up to the delimiter is skipped but still counted in the bytes read.
 type Line struct {
-	*bufio.Scanner
+	reader    io.Reader
+	encoding  Encoding
+	delimiter []byte
+	cr        []byte // the carriage return ending lines with the newline
+	strip     bool   // drop the delimiter from the records
+	maxBytes  int
+	chunk     []byte
+	buf       []byte // bytes read, those from pos on follow the last record
+	pos       int
+	err       error
+}
+
+// NewLine reads lines including their line ending.
+func NewLine(input io.Reader, encoding Encoding, bufferSize, maxBytes int) (*Line, error) {
+	return newLine(input, encoding, encoding.newline, false, bufferSize, maxBytes), nil
+}
+
+// NewDelimiter reads records ending in the delimiter, which is left out of
+// the records.
+func NewDelimiter(input io.Reader, encoding Encoding, delimiter string, bufferSize, maxBytes int) (*Line, error) {
+	d, err := encoding.encode(delimiter)
+	if err != nil {
+		return nil, fmt.Errorf("delimiter %q cannot be encoded in %s: %v", delimiter, encoding.Name, err)
+	}
+	return newLine(input, encoding, d, true, bufferSize, maxBytes), nil
+}
+
+func newLine(input io.Reader, encoding Encoding, delimiter []byte, strip bool, bufferSize, maxBytes int) *Line {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	// Cut at code unit boundaries, so the kept bytes still decode.
+	if maxBytes > 0 {
+		maxBytes -= maxBytes % encoding.width()
+	}
+
+	var cr []byte
+	if !strip {
+		cr, _ = encoding.encode("\r")
+	}
+
+	return &Line{
+		reader:    input,
+		encoding:  encoding,
+		delimiter: delimiter,
+		cr:        cr,
+		strip:     strip,
+		maxBytes:  maxBytes,
+		chunk:     make([]byte, bufferSize),
+	}
 }
 
-func NewLine(input io.Reader) (*Line, error) {
-	line := &Line{bufio.NewScanner(input)}
-	line.Split(bufio.ScanLines)
-	return line, nil
+// Next returns the next record, the number of bytes it consumed and whether
+// it was cut. An incomplete last record is returned at the end of the input,
+// io.EOF follows with the next call.
+func (l *Line) Next() ([]byte, int, bool, error) {
+	var line []byte
+	size := 0
+	dropped := false
+
+	for {
+		buf := l.buf[l.pos:]
+		if i := l.encoding.index(buf, l.delimiter); i >= 0 {
+			line, dropped = l.keep(line, buf[:i-len(l.delimiter)], dropped)
+			line, truncated := l.end(line, l.delimiter, dropped)
+			l.pos += i
+			return line, size + i, truncated, nil
+		}
+
+		// Without a delimiter, all complete code units of the buffer belong
+		// to the record, except for what may be the start of the delimiter.
+		n := len(buf) - (len(l.delimiter) - l.encoding.width())
+		if n -= n % l.encoding.width(); n > 0 {
+			line, dropped = l.keep(line, buf[:n], dropped)
+			l.pos += n
+			size += n
+		}
+
+		if l.err != nil {
+			// Only the end of the source completes a record. Other errors
+			// are returned by this and every later call, the part of the
+			// record read so far is dropped. The offset of the file still
+			// points at its start, so the next scanner reads it again.
+			buf = l.buf[l.pos:]
+			if l.err == io.EOF && (size > 0 || len(buf) > 0) {
+				line, dropped = l.keep(line, buf, dropped)
+				line, truncated := l.end(line, nil, dropped)
+				size += len(buf)
+				l.pos += len(buf)
+				return line, size, truncated, nil
+			}
+			return nil, 0, false, l.err
+		}
+
+		l.compact()
+		n, err := l.reader.Read(l.chunk)
+		l.buf = append(l.buf, l.chunk[:n]...)
+		l.err = err
+	}
+}
+
+// keep appends the bytes of the record as long as it is below maxBytes and
+// reports whether bytes were dropped. Room is left for the carriage return of
+// a line ending, which is only known to be one once the newline follows.
+func (l *Line) keep(line, b []byte, dropped bool) ([]byte, bool) {
+	if limit := l.maxBytes + len(l.cr); l.maxBytes > 0 && len(line)+len(b) > limit {
+		b = b[:limit-len(line)]
+		dropped = true
+	}
+	return append(line, b...), dropped
 }
 
-func (l *Line) Next() ([]byte, int, error) {
-	if l.Scan() && l.Err() == nil {
-		return l.Bytes(), len(l.Bytes()), nil
+// end completes the record kept of the content and the delimiter read, a
+// record cut at maxBytes is returned without its line ending.
+func (l *Line) end(line, delimiter []byte, dropped bool) ([]byte, bool) {
+	content, ending := line, delimiter
+	if len(delimiter) > 0 && !dropped && len(l.cr) > 0 && bytes.HasSuffix(line, l.cr) {
+		content = line[:len(line)-len(l.cr)]
 	}
 
-	return nil, 0, l.Err()
-}
\ No newline at end of file
+	if l.maxBytes > 0 && len(content) > l.maxBytes {
+		return content[:l.maxBytes], true
+	}
+	if dropped {
+		return content, true
+	}
+	if l.strip {
+		return content, false
+	}
+	return append(line, ending...), false
+}
+
+// compact moves the bytes following the last record to the start of the
+// buffer.
+func (l *Line) compact() {
+	if l.pos > 0 {
+		l.buf = l.buf[:copy(l.buf, l.buf[l.pos:])]
+		l.pos = 0
+	}
+}
@@ -0,0 +1,221 @@
+// +build !integration
+
+package reader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	content   string
+	bytes     int
+	truncated bool
+}
+
+// readAll reads the records of the framer until it fails.
+func readAll(t *testing.T, f Framer) ([]record, error) {
+	var records []record
+	for {
+		b, n, truncated, err := f.Next()
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record{string(b), n, truncated})
+		if len(records) > 100 {
+			t.Fatal("framer does not stop")
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxBytes int
+		records  []record
+	}{
+		{
+			name:  "lf",
+			input: "a\nbb\n\nccc\n",
+			records: []record{
+				{"a\n", 2, false},
+				{"bb\n", 3, false},
+				{"\n", 1, false},
+				{"ccc\n", 4, false},
+			},
+		},
+		{
+			name:  "crlf",
+			input: "a\r\nbb\r\n\r\nc\n",
+			records: []record{
+				{"a\r\n", 3, false},
+				{"bb\r\n", 4, false},
+				{"\r\n", 2, false},
+				{"c\n", 2, false},
+			},
+		},
+		{
+			name:  "partial last line",
+			input: "a\nbc",
+			records: []record{
+				{"a\n", 2, false},
+				{"bc", 2, false},
+			},
+		},
+		{
+			name:     "truncated",
+			input:    "abcdefgh\nij\nklmn\n",
+			maxBytes: 4,
+			records: []record{
+				{"abcd", 9, true},
+				{"ij\n", 3, false},
+				{"klmn\n", 5, false},
+			},
+		},
+		{
+			name:     "truncated crlf",
+			input:    "abc\r\nabcd\r\nabcde\r\n",
+			maxBytes: 4,
+			records: []record{
+				{"abc\r\n", 5, false},
+				{"abcd\r\n", 6, false},
+				{"abcd", 7, true},
+			},
+		},
+		{
+			name:     "truncated partial last line",
+			input:    "a\nbcdefg",
+			maxBytes: 3,
+			records: []record{
+				{"a\n", 2, false},
+				{"bcd", 6, true},
+			},
+		},
+		{
+			name:     "partial last line of max bytes",
+			input:    "a\nbcd\r",
+			maxBytes: 3,
+			records: []record{
+				{"a\n", 2, false},
+				{"bcd", 4, true},
+			},
+		},
+	}
+
+	// Small buffers make records cross the reads.
+	for _, bufferSize := range []int{1, 2, 3, 0} {
+		for _, test := range tests {
+			l, err := NewLine(strings.NewReader(test.input), Plain, bufferSize, test.maxBytes)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			records, err := readAll(t, l)
+			assert.Equal(t, io.EOF, err, "%s, buffer %d", test.name, bufferSize)
+			assert.Equal(t, test.records, records, "%s, buffer %d", test.name, bufferSize)
+		}
+	}
+}
+
+// TestLineResume reads the input again from the offset every line ends at,
+// the next line must start there.
+func TestLineResume(t *testing.T) {
+	input := "first\r\nsecond\n\r\nlast\n"
+
+	for offset := 0; offset < len(input); {
+		l, _ := NewLine(strings.NewReader(input[offset:]), Plain, 0, 0)
+		e, _ := NewEncode(l, Plain)
+		message, err := NewStripNewline(e).Next()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		rest := input[offset:]
+		line := rest[:strings.Index(rest, "\n")+1]
+		assert.Equal(t, len(line), message.Bytes, "offset %d", offset)
+		assert.Equal(t, strings.TrimRight(line, "\r\n"), string(message.Content), "offset %d", offset)
+		offset += message.Bytes
+	}
+}
+
+func TestLineUTF16(t *testing.T) {
+	// U+0A00 and U+0100 put the bytes of the newline at odd offsets, where
+	// they are no newline.
+	tests := []struct {
+		encoding string
+		input    string
+		maxBytes int
+		lines    []string
+		bytes    []int
+	}{
+		{"utf-16le", "\u0a00\u0100\u0a00\nb\n", 0, []string{"\u0a00\u0100\u0a00\n", "b\n"}, []int{8, 4}},
+		{"utf-16be", "\u0a00\u0100\u0a00\nb\n", 0, []string{"\u0a00\u0100\u0a00\n", "b\n"}, []int{8, 4}},
+		{"utf-16le", "\u0100\u0a00\r\n", 0, []string{"\u0100\u0a00\r\n"}, []int{8}},
+		// cut at the code unit below max_bytes
+		{"utf-16le", "abc\nd\n", 5, []string{"ab", "d\n"}, []int{8, 4}},
+		{"utf-16be", "abc\nd", 5, []string{"ab", "d"}, []int{8, 2}},
+		{"utf-16le", "ab\r\n", 4, []string{"ab\r\n"}, []int{8}},
+	}
+
+	for _, bufferSize := range []int{1, 3, 0} {
+		for _, test := range tests {
+			enc, err := FindEncoding(test.encoding)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			input, err := enc.encode(test.input)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			l, err := NewLine(strings.NewReader(string(input)), enc, bufferSize, test.maxBytes)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			e, _ := NewEncode(l, enc)
+
+			var lines []string
+			var bytes []int
+			for {
+				message, err := e.Next()
+				if err != nil {
+					assert.Equal(t, io.EOF, err)
+					break
+				}
+				lines = append(lines, string(message.Content))
+				bytes = append(bytes, message.Bytes)
+			}
+			assert.Equal(t, test.lines, lines, "%s %q, buffer %d", test.encoding, test.input, bufferSize)
+			assert.Equal(t, test.bytes, bytes, "%s %q, buffer %d", test.encoding, test.input, bufferSize)
+		}
+	}
+}
+
+// failingReader returns its content and fails afterwards.
+type failingReader struct {
+	io.Reader
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestLineError(t *testing.T) {
+	fail := errors.New("read failed")
+	l, _ := NewLine(&failingReader{strings.NewReader("a\nbc"), fail}, Plain, 0, 0)
+
+	// Only the end of the input completes the last line.
+	records, err := readAll(t, l)
+	assert.Equal(t, fail, err)
+	assert.Equal(t, []record{{"a\n", 2, false}}, records)
+}
@@ -34,4 +34,14 @@ func (msg *Message) AddFields(fields maps.StringIf) {
 		msg.Fields = maps.StringIf{}
 	}
 	msg.Fields.Update(fields)
+}
+
+// markTruncated marks the message as cut at max_bytes.
+func (msg *Message) markTruncated() {
+	if msg.Fields != nil && msg.Fields["truncated"] == true {
+		return
+	}
+
+	linesTruncated.Inc()
+	msg.AddFields(maps.StringIf{"truncated": true})
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package reader
+
+import (
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+var (
+	linesRead       = metric.NewCounter("reader_lines_total", "Lines read from sources.", nil)
+	linesTruncated  = metric.NewCounter("reader_truncated_total", "Messages cut at max_bytes.", nil)
+	jsonErrors      = metric.NewCounter("reader_json_errors_total", "Lines which failed to decode as JSON.", nil)
+	multilineEvents = metric.NewCounter("reader_multiline_events_total", "Events joined from several lines.", nil)
+	timestampErrors = metric.NewCounter("reader_timestamp_errors_total", "Messages whose timestamp could not be extracted.", nil)
+)
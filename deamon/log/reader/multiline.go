@@ -196,6 +196,9 @@ func (mlr *Multiline) clear() {
 
 func (mlr *Multiline) finalize() Message {
 	msg := mlr.message
+	if mlr.numLines > 1 {
+		multilineEvents.Inc()
+	}
 	mlr.clear()
 	return msg
 }
@@ -220,6 +223,9 @@ func (mlr *Multiline) addLine(m Message) {
 		if space < 0 || space > len(m.Content) {
 			space = len(m.Content)
 		}
+		if space < len(m.Content) {
+			mlr.message.markTruncated()
+		}
 
 		tmp := mlr.message.Content
 		if addSeparator {
@@ -227,6 +233,8 @@ func (mlr *Multiline) addLine(m Message) {
 		}
 		mlr.message.Content = append(tmp, m.Content[:space]...)
 		mlr.numLines++
+	} else {
+		mlr.message.markTruncated()
 	}
 
 	mlr.last = m.Content
@@ -0,0 +1,202 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+var (
+	syslogLayouts = []string{"Jan _2 15:04:05", "Jan _2 15:04:05.000000", "Jan _2 2006 15:04:05"}
+	apacheLayouts = []string{"02/Jan/2006:15:04:05 -0700", "Mon Jan 02 15:04:05.000000 2006", "Mon Jan 02 15:04:05 2006"}
+)
+
+// Timestamp sets the time of the messages to the timestamp they contain,
+// taken from a JSON field or the first group of a pattern. Messages whose
+// timestamp cannot be parsed keep the time they were read and get a
+// timestamp_error field, next to the fields of the message.
+type Timestamp struct {
+	reader   Reader
+	field    string
+	pattern  *regexp.Regexp
+	layouts  []string
+	location *time.Location
+	now      func() time.Time
+}
+
+func NewTimestamp(r Reader, config *TimestampConfig) (*Timestamp, error) {
+	location, err := config.location()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Timestamp{
+		reader:   r,
+		field:    config.Field,
+		layouts:  config.layouts(),
+		location: location,
+		now:      time.Now,
+	}
+
+	if config.Pattern != "" {
+		t.pattern, err = regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *Timestamp) Next() (Message, error) {
+	message, err := t.reader.Next()
+	if err != nil || message.IsEmpty() {
+		return message, err
+	}
+
+	value, err := t.value(&message)
+	if err == nil {
+		var ts time.Time
+		ts, err = t.parse(value)
+		if err == nil {
+			message.Ts = ts
+			return message, nil
+		}
+	}
+
+	timestampErrors.Inc()
+	message.AddFields(maps.StringIf{"timestamp_error": createTimestampError(err.Error())})
+	return message, nil
+}
+
+// value returns the timestamp the message contains.
+func (t *Timestamp) value(message *Message) (interface{}, error) {
+	if t.pattern == nil {
+		v, err := message.Fields.GetValue("json." + t.field)
+		if err != nil || v == nil {
+			return nil, fmt.Errorf("timestamp field '%s' not found", t.field)
+		}
+		return v, nil
+	}
+
+	groups := t.pattern.FindSubmatch(message.Content)
+	if groups == nil {
+		return nil, fmt.Errorf("timestamp pattern did not match")
+	}
+
+	// The group named timestamp, the first group, or the whole match.
+	for i, name := range t.pattern.SubexpNames() {
+		if name == "timestamp" {
+			return string(groups[i]), nil
+		}
+	}
+	if len(groups) > 1 {
+		return string(groups[1]), nil
+	}
+	return string(groups[0]), nil
+}
+
+// parse tries the layouts in order, the first one which fits wins.
+func (t *Timestamp) parse(value interface{}) (time.Time, error) {
+	for _, layout := range t.layouts {
+		if ts, ok := t.parseLayout(layout, value); ok {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("timestamp '%v' does not match any of the layouts %s", value, strings.Join(t.layouts, ", "))
+}
+
+func (t *Timestamp) parseLayout(layout string, value interface{}) (time.Time, bool) {
+	switch layout {
+	case LayoutUnix:
+		return parseEpoch(value, 1)
+	case LayoutUnixMs:
+		return parseEpoch(value, 1000)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	s = strings.TrimSpace(s)
+
+	switch layout {
+	case LayoutRFC3339:
+		return t.parseAny(s, time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999")
+	case LayoutSyslog:
+		ts, ok := t.parseAny(s, syslogLayouts...)
+		if ok && ts.Year() == 0 {
+			ts = t.withYear(ts)
+		}
+		return ts, ok
+	case LayoutApache:
+		return t.parseAny(s, apacheLayouts...)
+	}
+	return t.parseAny(s, layout)
+}
+
+func (t *Timestamp) parseAny(s string, layouts ...string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if ts, err := time.ParseInLocation(layout, s, t.location); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// withYear sets the year of a timestamp which has none to the current one,
+// or to the one before if the timestamp would lie more than a day ahead, e.g.
+// for December logs read in January.
+func (t *Timestamp) withYear(ts time.Time) time.Time {
+	now := t.now().In(t.location)
+	year := now.Year()
+	if date(year, ts).After(now.Add(24 * time.Hour)) {
+		year--
+	}
+	return date(year, ts)
+}
+
+func date(year int, ts time.Time) time.Time {
+	return time.Date(year, ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), ts.Location())
+}
+
+// parseEpoch parses seconds or fractions of them since the epoch, given as
+// number or string.
+func parseEpoch(value interface{}, perSecond int64) (time.Time, bool) {
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float64:
+		f = v
+	case json.Number:
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return time.Time{}, false
+		}
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	whole, frac := math.Modf(f)
+	unit := int64(time.Second) / perSecond
+	return time.Unix(0, int64(whole)*unit+int64(math.Round(frac*float64(unit)))).UTC(), true
+}
+
+func createTimestampError(message string) maps.StringIf {
+	return maps.StringIf{"message": message, "type": "timestamp"}
+}
@@ -0,0 +1,61 @@
+package reader
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	LayoutRFC3339 = "rfc3339"
+	LayoutSyslog  = "syslog"
+	LayoutApache  = "apache"
+	LayoutUnix    = "unix"
+	LayoutUnixMs  = "unix_ms"
+)
+
+type TimestampConfig struct {
+	Field    string   `config:"field"`
+	Pattern  string   `config:"pattern"`
+	Layouts  []string `config:"layouts"`
+	Timezone string   `config:"timezone"`
+}
+
+func (c *TimestampConfig) Validate() error {
+	if (c.Field == "") == (c.Pattern == "") {
+		return fmt.Errorf("timestamp requires either a field or a pattern")
+	}
+
+	if c.Pattern != "" {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return fmt.Errorf("invalid timestamp pattern: %v", err)
+		}
+	}
+
+	if _, err := c.location(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// layouts returns the layouts to try, RFC3339 if none are configured.
+func (c *TimestampConfig) layouts() []string {
+	if len(c.Layouts) == 0 {
+		return []string{LayoutRFC3339}
+	}
+	return c.Layouts
+}
+
+// location returns the timezone of timestamps which do not name one, the
+// local one if none is configured.
+func (c *TimestampConfig) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp timezone %s: %v", c.Timezone, err)
+	}
+	return loc, nil
+}
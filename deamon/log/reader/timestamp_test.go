@@ -0,0 +1,116 @@
+// +build !integration
+
+package reader
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/types/maps"
+)
+
+// messages returns the messages it holds, io.EOF afterwards.
+type messages []Message
+
+func (m *messages) Next() (Message, error) {
+	if len(*m) == 0 {
+		return Message{}, io.EOF
+	}
+	message := (*m)[0]
+	*m = (*m)[1:]
+	return message, nil
+}
+
+func TestTimestampSyslog(t *testing.T) {
+	tests := []struct {
+		now      string
+		line     string
+		expected string
+	}{
+		// December logs read in January are from the year before
+		{"2024-01-01T00:00:10Z", "Dec 31 23:59:59 host app: x", "2023-12-31T23:59:59Z"},
+		{"2024-01-01T00:00:10Z", "Jan  1 00:00:05 host app: x", "2024-01-01T00:00:05Z"},
+		// a clock slightly ahead of the reader keeps the year
+		{"2024-01-01T00:00:10Z", "Jan  1 12:00:00 host app: x", "2024-01-01T12:00:00Z"},
+		{"2024-06-15T08:00:00Z", "Jun 14 23:00:00.250000 host app: x", "2024-06-14T23:00:00.25Z"},
+		{"2024-06-15T08:00:00Z", "Jun 14 2019 23:00:00 host app: x", "2019-06-14T23:00:00Z"},
+	}
+
+	for _, test := range tests {
+		now, _ := time.Parse(time.RFC3339, test.now)
+		source := messages{{Content: []byte(test.line), Bytes: len(test.line)}}
+
+		ts, err := NewTimestamp(&source, &TimestampConfig{
+			Pattern:  `^(\w{3} [ \d]\d(?: \d{4})? [\d:.]+)`,
+			Layouts:  []string{LayoutSyslog},
+			Timezone: "UTC",
+		})
+		if !assert.NoError(t, err) {
+			continue
+		}
+		ts.now = func() time.Time { return now }
+
+		message, err := ts.Next()
+		assert.NoError(t, err)
+		assert.Nil(t, message.Fields, test.line)
+		assert.Equal(t, test.expected, message.Ts.Format(time.RFC3339Nano), test.line)
+	}
+}
+
+func TestTimestampEpoch(t *testing.T) {
+	tests := []struct {
+		layout   string
+		value    interface{}
+		expected time.Time
+	}{
+		{LayoutUnixMs, json.Number("1700000000123"), time.Unix(1700000000, 123000000)},
+		{LayoutUnixMs, int64(1700000000123), time.Unix(1700000000, 123000000)},
+		{LayoutUnixMs, "1700000000123", time.Unix(1700000000, 123000000)},
+		{LayoutUnixMs, 1700000000123.5, time.Unix(1700000000, 123500000)},
+		{LayoutUnixMs, json.Number("-1"), time.Unix(0, -1000000)},
+		{LayoutUnix, json.Number("1700000000"), time.Unix(1700000000, 0)},
+		{LayoutUnix, "1700000000.25", time.Unix(1700000000, 250000000)},
+	}
+
+	for _, test := range tests {
+		source := messages{{
+			Content: []byte("{}"),
+			Bytes:   2,
+			Fields:  maps.StringIf{"json": maps.StringIf{"time": test.value}},
+		}}
+
+		ts, err := NewTimestamp(&source, &TimestampConfig{Field: "time", Layouts: []string{test.layout}})
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		message, err := ts.Next()
+		assert.NoError(t, err)
+		assert.True(t, test.expected.Equal(message.Ts), "%s %v: %v", test.layout, test.value, message.Ts)
+		assert.Nil(t, message.Fields["timestamp_error"])
+	}
+}
+
+func TestTimestampError(t *testing.T) {
+	// the error of the message itself is kept
+	source := messages{{Content: []byte("no time"), Bytes: 7, Fields: maps.StringIf{"error": "own"}}}
+	ts, err := NewTimestamp(&source, &TimestampConfig{Pattern: `^(\d+)`, Layouts: []string{LayoutUnix}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	message, err := ts.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "no time", string(message.Content))
+	assert.Equal(t, "own", message.Fields["error"])
+	if assert.NotNil(t, message.Fields["timestamp_error"]) {
+		assert.Equal(t, "timestamp", message.Fields["timestamp_error"].(maps.StringIf)["type"])
+	}
+
+	_, err = ts.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/satori/go.uuid"
-	"golang.org/x/text/transform"
 
 	"github.com/queueio/sentry/utils/log"
 cfg	"github.com/queueio/sentry/utils/config"
@@ -20,6 +19,7 @@ cfg	"github.com/queueio/sentry/utils/config"
 
 	"github.com/queueio/sentry/components/scribe"
 	"github.com/queueio/sentry/components/scribe/log/reader"
+	"github.com/queueio/sentry/components/scribe/metric"
 
 )
 
@@ -41,18 +41,23 @@ type Scanner struct {
 	stopWg   *sync.WaitGroup
 
 	state     scribe.State
+	stateLock sync.RWMutex // guards state and source against Status
 	states   *scribe.States
+	outlet   *scribe.Outlet
 	log      *Log
 
+	encoding  reader.Encoding
 	reader    reader.Reader
 	publisher outputs.Publisher
+	metrics  *metric.Input
 }
 
-func NewScanner(config *cfg.Config, state scribe.State, states *scribe.States, pub outputs.Publisher) (*Scanner, error) {
+func NewScanner(config *cfg.Config, state scribe.State, states *scribe.States, outlet *scribe.Outlet, pub outputs.Publisher) (*Scanner, error) {
 	s := &Scanner{
 		config:     defaultConfig,
 		state:      state,
 		states:     states,
+		outlet:     outlet,
 		publisher:  pub,
 		done:       make(chan struct{}),
 		stopWg:     &sync.WaitGroup{},
@@ -63,6 +68,13 @@ func NewScanner(config *cfg.Config, state scribe.State, states *scribe.States, p
 		return nil, err
 	}
 
+	var err error
+	s.encoding, err = reader.FindEncoding(s.config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics = metric.NewInput(s.config.Name)
+
 	if s.config.State.Clean.Inactive > 0 {
 		s.state.TTL = s.config.State.Clean.Inactive
 	}
@@ -73,6 +85,8 @@ func (s *Scanner) open() error {
 	switch s.config.Type {
 	case scribe.LogType:
 		return s.openFile()
+	case scribe.StdinType:
+		return s.openStdin()
 	default:
 		return fmt.Errorf("Invalid scanner type: %+v", s.config)
 	}
@@ -145,7 +159,9 @@ func (w *Scanner) Run() error {
 			switch err {
 			case ErrFileTruncate:
 				log.Info("File was truncated. Begin reading file from offset 0: %s", w.state.Source)
+				w.stateLock.Lock()
 				w.state.Offset = 0
+				w.stateLock.Unlock()
 			case ErrRemoved:
 				log.Info("File was removed: %s. Closing because close_removed is enabled.", w.state.Source)
 			case ErrRenamed:
@@ -162,12 +178,14 @@ func (w *Scanner) Run() error {
 			return nil
 		}
 
-		if w.state.Offset == 0 {
-			message.Content = bytes.Trim(message.Content, "\xef\xbb\xbf")
+		// the byte order mark only heads the first line of a file
+		if w.source.HasState() && w.state.Offset == 0 {
+			message.Content = bytes.TrimPrefix(message.Content, []byte("\xef\xbb\xbf"))
 		}
 
 		state := w.getState()
 		state.Offset += int64(message.Bytes)
+		w.metrics.BytesRead.Add(uint64(message.Bytes))
 
 		data := scribe.NewData()
 		if w.source.HasState() {
@@ -176,6 +194,7 @@ func (w *Scanner) Run() error {
 
 		text := string(message.Content)
 		if !message.IsEmpty() && w.shouldExportLine(text) {
+			w.metrics.EventsRead.Inc()
 			fields := maps.StringIf{
 				"source": state.Source,
 				"offset": state.Offset, // Offset here is the offset before the starting char.
@@ -208,8 +227,37 @@ func (w *Scanner) Run() error {
 			return nil
 		}
 
+		w.stateLock.Lock()
 		w.state = state
+		w.stateLock.Unlock()
+	}
+}
+
+// Status describes the file the scanner reads and how far behind it is.
+func (s *Scanner) Status() scribe.ScannerStatus {
+	s.stateLock.RLock()
+	state, source := s.state, s.source
+	s.stateLock.RUnlock()
+
+	status := scribe.ScannerStatus{
+		ID:     s.id.String(),
+		Source: state.Source,
+		Inode:  state.FileStateOS.Inode,
+		Device: state.FileStateOS.Device,
+		Offset: state.Offset,
+	}
+
+	if source == nil || !source.HasState() {
+		return status
 	}
+
+	if info, err := os.Stat(state.Source); err == nil {
+		status.Size = info.Size()
+		if lag := status.Size - status.Offset; lag > 0 {
+			status.Lag = lag
+		}
+	}
+	return status
 }
 
 func (s *Scanner) stop() {
@@ -228,7 +276,7 @@ func (s *Scanner) sendEvent(data *scribe.Data) bool {
 		s.states.Update(data.GetState())
 	}
 
-	err := s.publisher.Publish(data.Event)
+	err := s.outlet.Publish(s.publisher, data)
 	return err == nil
 }
 
@@ -239,20 +287,20 @@ func (s *Scanner) SendStateUpdate() {
 
 	log.Debug("scanner", "Update state: %s, offset: %v", s.state.Source, s.state.Offset)
 	s.states.Update(s.state)
-
-	d := scribe.NewData()
-	d.SetState(s.state)
+	s.outlet.Update(s.state)
 }
 
 func (s *Scanner) shouldExportLine(line string) bool {
 	if len(s.config.Include.Lines) > 0 {
 		if !scribe.MatchAny(s.config.Include.Lines, line) {
+			s.metrics.EventsFiltered.Inc()
 			log.Debug("scanner", "Drop line as it does not match any of the include patterns %s", line)
 			return false
 		}
 	}
 	if len(s.config.Exclude.Lines) > 0 {
 		if scribe.MatchAny(s.config.Exclude.Lines, line) {
+			s.metrics.EventsFiltered.Inc()
 			log.Debug("scanner", "Drop line as it does match one of the exclude patterns%s", line)
 			return false
 		}
@@ -273,7 +321,17 @@ func (s *Scanner) openFile() error {
 		return err
 	}
 
+	s.stateLock.Lock()
 	s.source = File{File: f}
+	s.stateLock.Unlock()
+	s.metrics.OpenFiles.Inc()
+	return nil
+}
+
+func (s *Scanner) openStdin() error {
+	s.stateLock.Lock()
+	s.source = Pipe{File: os.Stdin}
+	s.stateLock.Unlock()
 	return nil
 }
 
@@ -291,26 +349,37 @@ func (s *Scanner) validateFile(f *os.File) error {
 		return errors.New("file info is not identical with opened file. Aborting harvesting and retrying file later again")
 	}
 
-	if err != nil {
-		if err == transform.ErrShortSrc {
-			log.Info("Initialising encoding for '%v' failed due to file being too short", f)
-		} else {
-			log.Err("Initialising encoding for '%v' failed: %v", f, err)
-		}
-		return err
-	}
-
 	offset, err := s.initFileOffset(f)
 	if err != nil {
 		return err
 	}
 
 	log.Debug("scanner", "Setting offset for file: %s. Offset: %d ", s.state.Source, offset)
+	s.stateLock.Lock()
 	s.state.Offset = offset
+	s.stateLock.Unlock()
 
+	// the byte order mark heads the file whatever offset reading resumes at
+	s.detectEncoding(f)
 	return nil
 }
 
+// detectEncoding reads the byte order mark at the start of a file, it takes
+// precedence over the encoding configured.
+func (s *Scanner) detectEncoding(f *os.File) {
+	head := make([]byte, 3)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		log.Warn("Reading byte order mark of %s failed: %v", s.state.Source, err)
+		return
+	}
+
+	if enc, ok := reader.DetectBOM(head[:n]); ok && enc.Name != s.encoding.Name {
+		log.Info("Byte order mark of %s announces %s, using it instead of %s", s.state.Source, enc.Name, s.encoding.Name)
+		s.encoding = enc
+	}
+}
+
 func (s *Scanner) initFileOffset(file *os.File) (int64, error) {
 	if s.state.Offset > 0 {
 		log.Debug("scanner", "Set previous offset for file: %s. Offset: %d ", s.state.Source, s.state.Offset)
@@ -331,13 +400,18 @@ func (s *Scanner) getState() scribe.State {
 }
 
 func (s *Scanner) cleanup() {
+	s.stateLock.Lock()
 	s.state.Finished = true
+	s.stateLock.Unlock()
 
 	log.Debug("scanner", "Stopping scanner for file: %s", s.state.Source)
 	defer log.Debug("scanner", "scanner cleanup finished for file: %s", s.state.Source)
 
 	if s.source != nil {
 		s.source.Close()
+		if _, ok := s.source.(File); ok {
+			s.metrics.OpenFiles.Dec()
+		}
 
 		log.Debug("scanner", "Closing file: %s", s.state.Source)
 		s.SendStateUpdate()
@@ -355,7 +429,12 @@ func (s *Scanner) newLogFileReader() (reader.Reader, error) {
 		return nil, err
 	}
 
-	r, err = reader.NewEncode(s.log)
+	framer, err := reader.NewFramer(s.log, s.encoding, s.config.Framing, s.config.Scanner.Buffer, s.config.Max.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err = reader.NewEncode(framer, s.encoding)
 	if err != nil {
 		return nil, err
 	}
@@ -364,7 +443,9 @@ func (s *Scanner) newLogFileReader() (reader.Reader, error) {
 		r = reader.NewJSON(r, s.config.JSON)
 	}
 
-	r = reader.NewStripNewline(r)
+	if s.config.Framing.IsNewline() {
+		r = reader.NewStripNewline(r)
+	}
 
 	if s.config.Multiline != nil {
 		r, err = reader.NewMultiLine(r, "\n", s.config.Max.Bytes, s.config.Multiline)
@@ -373,5 +454,12 @@ func (s *Scanner) newLogFileReader() (reader.Reader, error) {
 		}
 	}
 
+	if s.config.Timestamp != nil {
+		r, err = reader.NewTimestamp(r, s.config.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return reader.NewLimit(r, s.config.Max.Bytes), nil
 }
\ No newline at end of file
@@ -0,0 +1,66 @@
+// +build !integration
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/queueio/sentry/utils/config"
+
+	"github.com/queueio/sentry/components/scribe"
+)
+
+func TestScannerResumesBOMFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("first\n"))
+	second, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("second\n"))
+	bom := []byte{0xFF, 0xFE}
+
+	path := filepath.Join(dir, "utf16.log")
+	content := append(append(append([]byte{}, bom...), first...), second...)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadStream([]byte("paths: [" + path + "]\nscanner:\n  close:\n    eof: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := scribe.NewState(info, path, scribe.LogType)
+	state.Offset = int64(len(bom) + len(first))
+
+	s, err := NewScanner(c, state, scribe.NewStates(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.source.Close()
+
+	assert.Equal(t, "utf-16le", s.encoding.Name)
+
+	message, err := s.reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "second", string(message.Content))
+	assert.Equal(t, len(second), message.Bytes)
+}
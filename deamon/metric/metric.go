@@ -1 +1,202 @@
+// Package metric keeps the internal metrics of the agent. Collectors, readers
+// and outputs register counters, gauges and histograms by name and labels in
+// a registry, reporters read them back through snapshots.
 package metric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Type string
+
+const (
+	CounterType   Type = "counter"
+	GaugeType     Type = "gauge"
+	HistogramType Type = "histogram"
+)
+
+// Labels tell apart the metrics of one name, like the output an event
+// counter belongs to.
+type Labels map[string]string
+
+// String formats the labels sorted by their name, e.g. {input="a",type="log"}.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, l[name])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (l Labels) copy() Labels {
+	c := make(Labels, len(l))
+	for k, v := range l {
+		c[k] = v
+	}
+	return c
+}
+
+// Metric is implemented by Counter, Gauge and Histogram.
+type Metric interface {
+	Desc() *Desc
+}
+
+// Desc describes a registered metric.
+type Desc struct {
+	Name   string
+	Help   string
+	Type   Type
+	Labels Labels
+}
+
+// Key identifies the metric in its registry.
+func (d *Desc) Key() string {
+	return d.Name + d.Labels.String()
+}
+
+// Registry holds metrics by their name and labels.
+type Registry struct {
+	sync.RWMutex
+	metrics map[string]Metric
+	help    map[string]string
+}
+
+// Default is the registry the package level functions register with.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: map[string]Metric{},
+		help:    map[string]string{},
+	}
+}
+
+// Counter returns the counter of the name and labels, registering it on
+// first use.
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	return r.register(name, help, CounterType, labels, func(d Desc) Metric {
+		return &Counter{desc: d}
+	}).(*Counter)
+}
+
+// Gauge returns the gauge of the name and labels, registering it on first
+// use.
+func (r *Registry) Gauge(name, help string, labels Labels) *Gauge {
+	return r.register(name, help, GaugeType, labels, func(d Desc) Metric {
+		return &Gauge{desc: d}
+	}).(*Gauge)
+}
+
+// Histogram returns the histogram of the name and labels, registering it on
+// first use. Without buckets DefaultBuckets are used.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels Labels) *Histogram {
+	return r.register(name, help, HistogramType, labels, func(d Desc) Metric {
+		return newHistogram(d, buckets)
+	}).(*Histogram)
+}
+
+func (r *Registry) register(name, help string, t Type, labels Labels, create func(Desc) Metric) Metric {
+	desc := Desc{Name: name, Help: help, Type: t, Labels: labels.copy()}
+	key := desc.Key()
+
+	r.RLock()
+	m, ok := r.metrics[key]
+	r.RUnlock()
+
+	if !ok {
+		r.Lock()
+		if m, ok = r.metrics[key]; !ok {
+			for _, other := range r.metrics {
+				if d := other.Desc(); d.Name == name && d.Type != t {
+					r.Unlock()
+					panic(fmt.Errorf("metric %s registered as %s already", name, d.Type))
+				}
+			}
+
+			m = create(desc)
+			r.metrics[key] = m
+			if help != "" {
+				r.help[name] = help
+			}
+		}
+		r.Unlock()
+	}
+
+	if d := m.Desc(); d.Type != t {
+		panic(fmt.Errorf("metric %s registered as %s already", name, d.Type))
+	}
+	return m
+}
+
+// Unregister removes the metric of the name and labels, e.g. once the file
+// it was counting for is closed.
+func (r *Registry) Unregister(name string, labels Labels) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.metrics, name+labels.String())
+}
+
+// Help returns the help text registered for the metric name.
+func (r *Registry) Help(name string) string {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.help[name]
+}
+
+// Metrics returns all registered metrics sorted by name and labels.
+func (r *Registry) Metrics() []Metric {
+	r.RLock()
+	keys := make([]string, 0, len(r.metrics))
+	for key := range r.metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]Metric, len(keys))
+	for i, key := range keys {
+		metrics[i] = r.metrics[key]
+	}
+	r.RUnlock()
+
+	sort.SliceStable(metrics, func(i, j int) bool {
+		return metrics[i].Desc().Name < metrics[j].Desc().Name
+	})
+	return metrics
+}
+
+// NewCounter returns the counter of the name and labels in the default
+// registry.
+func NewCounter(name, help string, labels Labels) *Counter {
+	return Default.Counter(name, help, labels)
+}
+
+// NewGauge returns the gauge of the name and labels in the default registry.
+func NewGauge(name, help string, labels Labels) *Gauge {
+	return Default.Gauge(name, help, labels)
+}
+
+// NewHistogram returns the histogram of the name and labels in the default
+// registry.
+func NewHistogram(name, help string, buckets []float64, labels Labels) *Histogram {
+	return Default.Histogram(name, help, buckets, labels)
+}
+
+// Unregister removes the metric from the default registry.
+func Unregister(name string, labels Labels) {
+	Default.Unregister(name, labels)
+}
@@ -0,0 +1,33 @@
+// +build !integration
+
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	c := registry.Counter("events_total", "Events.", Labels{"output": "a"})
+	assert.Equal(t, c, registry.Counter("events_total", "", Labels{"output": "a"}))
+	assert.NotEqual(t, c, registry.Counter("events_total", "", Labels{"output": "b"}))
+	assert.Equal(t, "Events.", registry.Help("events_total"))
+
+	registry.Unregister("events_total", Labels{"output": "a"})
+	assert.Len(t, registry.Metrics(), 1)
+}
+
+func TestRegistryTypeConflict(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("events_total", "", Labels{"output": "a"})
+
+	assert.Panics(t, func() {
+		registry.Gauge("events_total", "", Labels{"output": "a"})
+	})
+	assert.Panics(t, func() {
+		registry.Histogram("events_total", "", nil, Labels{"output": "b"})
+	})
+}
@@ -0,0 +1,45 @@
+package metric
+
+// Input holds the metrics every collector updates, labeled by the name of
+// the input.
+type Input struct {
+	EventsRead     *Counter
+	BytesRead      *Counter
+	EventsFiltered *Counter
+	OpenFiles      *Gauge
+	Files          *Gauge
+	Scanners       *Gauge
+}
+
+func NewInput(name string) *Input {
+	labels := Labels{"input": name}
+	return &Input{
+		EventsRead:     NewCounter("input_events_read_total", "Events read by the input.", labels),
+		BytesRead:      NewCounter("input_bytes_read_total", "Bytes read by the input.", labels),
+		EventsFiltered: NewCounter("input_events_filtered_total", "Events dropped by include and exclude patterns.", labels),
+		OpenFiles:      NewGauge("input_open_files", "Files currently open by the input.", labels),
+		Files:          NewGauge("input_files", "Files matched by the paths of the input at the last scan.", labels),
+		Scanners:       NewGauge("input_scanners", "Scanners running for the input.", labels),
+	}
+}
+
+// Output holds the metrics every output updates, labeled by the type of the
+// output.
+type Output struct {
+	Published *Counter
+	Acked     *Counter
+	Failed    *Counter
+	Dropped   *Counter
+	Latency   *Histogram
+}
+
+func NewOutput(name string) *Output {
+	labels := Labels{"output": name}
+	return &Output{
+		Published: NewCounter("output_events_published_total", "Events handed to the output.", labels),
+		Acked:     NewCounter("output_events_acked_total", "Events the output delivered.", labels),
+		Failed:    NewCounter("output_events_failed_total", "Events of failed delivery attempts.", labels),
+		Dropped:   NewCounter("output_events_dropped_total", "Events the output gave up on.", labels),
+		Latency:   NewHistogram("output_publish_latency_seconds", "Time a delivery attempt took.", nil, labels),
+	}
+}
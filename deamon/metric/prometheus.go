@@ -0,0 +1,163 @@
+package metric
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var startTime = time.Now()
+
+// WritePrometheus writes the metrics of the registry in the Prometheus text
+// format, the names prefixed by the namespace. Go runtime stats follow under
+// their usual go_ and process_ names.
+func WritePrometheus(w io.Writer, r *Registry, namespace string) error {
+	b := bufio.NewWriter(w)
+
+	if namespace != "" {
+		namespace = sanitize(namespace) + "_"
+	}
+
+	last := ""
+	for _, m := range r.Metrics() {
+		d := m.Desc()
+		name := namespace + d.Name
+		if d.Name != last {
+			writeHeader(b, name, r.Help(d.Name), d.Type)
+			last = d.Name
+		}
+
+		switch m := m.(type) {
+		case *Counter:
+			writeSample(b, name, d.Labels, "", "", float64(m.Get()))
+		case *Gauge:
+			writeSample(b, name, d.Labels, "", "", float64(m.Get()))
+		case *Histogram:
+			s := m.Snapshot()
+			for i, bound := range s.Buckets {
+				writeSample(b, name+"_bucket", d.Labels, "le", formatFloat(bound), float64(s.Counts[i]))
+			}
+			writeSample(b, name+"_bucket", d.Labels, "le", "+Inf", float64(s.Count))
+			writeSample(b, name+"_sum", d.Labels, "", "", s.Sum)
+			writeSample(b, name+"_count", d.Labels, "", "", float64(s.Count))
+		}
+	}
+
+	writeRuntime(b)
+	return b.Flush()
+}
+
+func writeRuntime(b *bufio.Writer) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	gauges := []struct {
+		name  string
+		help  string
+		t     Type
+		value float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", GaugeType, float64(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", GaugeType, float64(stats.Alloc)},
+		{"go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", CounterType, float64(stats.TotalAlloc)},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", GaugeType, float64(stats.Sys)},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", GaugeType, float64(stats.HeapInuse)},
+		{"go_memstats_heap_objects", "Number of allocated objects.", GaugeType, float64(stats.HeapObjects)},
+		{"go_memstats_mallocs_total", "Total number of mallocs.", CounterType, float64(stats.Mallocs)},
+		{"go_memstats_frees_total", "Total number of frees.", CounterType, float64(stats.Frees)},
+		{"go_gc_runs_total", "Number of completed GC cycles.", CounterType, float64(stats.NumGC)},
+		{"go_gc_pause_seconds_total", "Total time spent in GC pauses.", CounterType, float64(stats.PauseTotalNs) / 1e9},
+		{"process_start_time_seconds", "Start time of the process since unix epoch in seconds.", GaugeType, float64(startTime.UnixNano()) / 1e9},
+	}
+
+	for _, g := range gauges {
+		writeHeader(b, g.name, g.help, g.t)
+		writeSample(b, g.name, nil, "", "", g.value)
+	}
+
+	writeHeader(b, "go_info", "Information about the Go environment.", GaugeType)
+	writeSample(b, "go_info", Labels{"version": runtime.Version()}, "", "", 1)
+}
+
+func writeHeader(b *bufio.Writer, name, help string, t Type) {
+	if help != "" {
+		fmt.Fprintf(b, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, t)
+}
+
+// writeSample writes one line, with the extra label appended to the labels
+// of the metric if given.
+func writeSample(b *bufio.Writer, name string, labels Labels, extra, value string, v float64) {
+	b.WriteString(name)
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if extra != "" {
+		names = append(names, extra)
+	}
+
+	if len(names) > 0 {
+		b.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			label := labels[name]
+			if name == extra {
+				label = value
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", sanitize(name), escapeLabel(label))
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// sanitize replaces the characters which are not allowed in metric and label
+// names.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
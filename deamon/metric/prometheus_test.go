@@ -0,0 +1,44 @@
+// +build !integration
+
+package metric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("events_total", "Events.\nAll of them.", Labels{"output": "x"}).Add(2)
+	registry.Counter("events_total", "", Labels{"output": "a\"b\\c\nd"}).Inc()
+
+	latency := registry.Histogram("latency_seconds", "Latency.", []float64{0.3, 1}, Labels{"input": "i"})
+	latency.Observe(0.25)
+	latency.Observe(0.5)
+	latency.Observe(4)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WritePrometheus(&buf, registry, "my-agent"))
+
+	expected := `# HELP my_agent_events_total Events.\nAll of them.
+# TYPE my_agent_events_total counter
+my_agent_events_total{output="a\"b\\c\nd"} 1
+my_agent_events_total{output="x"} 2
+# HELP my_agent_latency_seconds Latency.
+# TYPE my_agent_latency_seconds histogram
+my_agent_latency_seconds_bucket{input="i",le="0.3"} 1
+my_agent_latency_seconds_bucket{input="i",le="1"} 2
+my_agent_latency_seconds_bucket{input="i",le="+Inf"} 3
+my_agent_latency_seconds_sum{input="i"} 4.75
+my_agent_latency_seconds_count{input="i"} 3
+`
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, expected), out)
+
+	// the runtime stats follow without the namespace
+	assert.Contains(t, out, "\n# TYPE go_goroutines gauge\n")
+	assert.Contains(t, out, "\ngo_info{version=")
+}
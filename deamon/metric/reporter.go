@@ -0,0 +1,156 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/queueio/sentry/utils/log"
+)
+
+// Reporter periodically logs the metrics which changed: the deltas of
+// counters and histogram counts, and the values of gauges.
+type Reporter struct {
+	registry *Registry
+	period   time.Duration
+	last     map[string]float64
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// StartReporter logs the changes of the default registry every period.
+func StartReporter(period time.Duration) *Reporter {
+	r := &Reporter{
+		registry: Default,
+		period:   period,
+		last:     map[string]float64{},
+		done:     make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *Reporter) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+func (r *Reporter) report() {
+	changed := r.deltas()
+	if len(changed) == 0 {
+		log.Info("No non-zero metrics in the last %v", r.period)
+		return
+	}
+	log.InfoFields(fmt.Sprintf("Non-zero metrics in the last %v", r.period),
+		map[string]interface{}{"metrics": changed})
+}
+
+// deltas returns the metrics which changed since the last call. Metrics
+// which were unregistered are forgotten, a counter which went down was
+// registered anew and reports its new value.
+func (r *Reporter) deltas() map[string]interface{} {
+	deltas := map[string]interface{}{}
+	seen := map[string]bool{}
+	for _, m := range r.registry.Metrics() {
+		d := m.Desc()
+		key := reportKey(d)
+
+		var value float64
+		switch m := m.(type) {
+		case *Counter:
+			value = float64(m.Get())
+		case *Gauge:
+			value = float64(m.Get())
+		case *Histogram:
+			value = float64(m.Snapshot().Count)
+		}
+
+		seen[key] = true
+		last := r.last[key]
+		r.last[key] = value
+		if value == last {
+			continue
+		}
+
+		switch {
+		case d.Type == GaugeType:
+			deltas[key] = int64(value)
+		case value < last:
+			deltas[key] = uint64(value)
+		default:
+			deltas[key] = uint64(value - last)
+		}
+	}
+
+	for key := range r.last {
+		if !seen[key] {
+			delete(r.last, key)
+		}
+	}
+	return deltas
+}
+
+// Stop logs the totals of all non-zero metrics and stops the reporter.
+func (r *Reporter) Stop() {
+	close(r.done)
+	r.wg.Wait()
+
+	totals := map[string]interface{}{}
+	for _, m := range r.registry.Metrics() {
+		key := reportKey(m.Desc())
+		switch m := m.(type) {
+		case *Counter:
+			if v := m.Get(); v != 0 {
+				totals[key] = v
+			}
+		case *Gauge:
+			if v := m.Get(); v != 0 {
+				totals[key] = v
+			}
+		case *Histogram:
+			if v := m.Snapshot().Count; v != 0 {
+				totals[key] = v
+			}
+		}
+	}
+
+	if len(totals) > 0 {
+		log.InfoFields("Total non-zero metrics", map[string]interface{}{"metrics": totals})
+	}
+}
+
+// reportKey names the metric by its name and label values, e.g.
+// output_events_acked_total.node. Histograms report their count.
+func reportKey(d *Desc) string {
+	labels := make([]string, 0, len(d.Labels))
+	for label := range d.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	name := d.Name
+	if d.Type == HistogramType {
+		name += "_count"
+	}
+
+	parts := []string{name}
+	for _, label := range labels {
+		parts = append(parts, d.Labels[label])
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,41 @@
+// +build !integration
+
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporterDeltas(t *testing.T) {
+	registry := NewRegistry()
+	r := &Reporter{registry: registry, last: map[string]float64{}}
+
+	labels := Labels{"output": "node"}
+	events := registry.Counter("events_total", "", labels)
+	files := registry.Gauge("open_files", "", nil)
+
+	events.Add(5)
+	files.Set(2)
+	assert.Equal(t, map[string]interface{}{
+		"events_total.node": uint64(5),
+		"open_files":        int64(2),
+	}, r.deltas())
+
+	events.Add(3)
+	assert.Equal(t, map[string]interface{}{"events_total.node": uint64(3)}, r.deltas())
+	assert.Empty(t, r.deltas())
+
+	// registered anew, the counter starts over
+	registry.Unregister("events_total", labels)
+	assert.Empty(t, r.deltas())
+	assert.NotContains(t, r.last, "events_total.node")
+
+	registry.Counter("events_total", "", labels).Add(2)
+	assert.Equal(t, map[string]interface{}{"events_total.node": uint64(2)}, r.deltas())
+
+	// a counter which went down reports its new value
+	r.last["events_total.node"] = 10
+	assert.Equal(t, map[string]interface{}{"events_total.node": uint64(2)}, r.deltas())
+}
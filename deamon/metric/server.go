@@ -0,0 +1,90 @@
+package metric
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/log"
+)
+
+// ServerConfig configures the HTTP listener exposing the metrics.
+type ServerConfig struct {
+	Host string `config:"host"`
+	Port int    `config:"port" validate:"min=0,max=65535"`
+	Path string `config:"path"`
+}
+
+var defaultServerConfig = ServerConfig{
+	Host: "localhost",
+	Port: 9479,
+	Path: "/metrics",
+}
+
+func (c *ServerConfig) Validate() error {
+	if !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("metrics path %q must start with /", c.Path)
+	}
+	return nil
+}
+
+// Server exposes the metrics of a registry to Prometheus.
+type Server struct {
+	registry  *Registry
+	namespace string
+	listener  net.Listener
+	server    *http.Server
+}
+
+// Start listens for scrapes of the default registry, the metric names
+// prefixed by the namespace.
+func Start(cfg *config.Config, namespace string) (*Server, error) {
+	c := defaultServerConfig
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(c.Host, fmt.Sprint(c.Port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %s: %v", address, err)
+	}
+
+	s := &Server{
+		registry:  Default,
+		namespace: strings.ToLower(namespace),
+		listener:  listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(c.Path, s.handle)
+	s.server = &http.Server{Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Err("Metrics server stopped: %v", err)
+		}
+	}()
+
+	log.Info("Metrics available at http://%s%s", listener.Addr(), c.Path)
+	return s, nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := WritePrometheus(w, s.registry, s.namespace); err != nil {
+		log.Err("Writing metrics failed: %v", err)
+	}
+}
+
+func (s *Server) Stop() {
+	s.server.Close()
+}
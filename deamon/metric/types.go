@@ -0,0 +1,139 @@
+package metric
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter only goes up, like the number of events read.
+type Counter struct {
+	desc  Desc
+	value uint64
+}
+
+func (c *Counter) Desc() *Desc {
+	return &c.desc
+}
+
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+func (c *Counter) Get() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+// Gauge goes up and down, like the number of open files.
+type Gauge struct {
+	desc  Desc
+	value int64
+}
+
+func (g *Gauge) Desc() *Desc {
+	return &g.desc
+}
+
+func (g *Gauge) Set(v int64) {
+	atomic.StoreInt64(&g.value, v)
+}
+
+func (g *Gauge) Inc() {
+	atomic.AddInt64(&g.value, 1)
+}
+
+func (g *Gauge) Dec() {
+	atomic.AddInt64(&g.value, -1)
+}
+
+func (g *Gauge) Add(n int64) {
+	atomic.AddInt64(&g.value, n)
+}
+
+func (g *Gauge) Get() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+// DefaultBuckets fit latencies in seconds, from 1ms to 10s.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in buckets, like publish latencies.
+type Histogram struct {
+	sync.Mutex
+	desc    Desc
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// HistogramSnapshot is a consistent copy of a histogram. Counts are
+// cumulative, Counts[i] holds the observations <= Buckets[i].
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+func newHistogram(desc Desc, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{
+		desc:    desc,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Desc() *Desc {
+	return &h.desc
+}
+
+func (h *Histogram) Observe(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Since observes the seconds passed since start.
+func (h *Histogram) Since(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.Lock()
+	defer h.Unlock()
+
+	s := HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+
+	var total uint64
+	for i, n := range h.counts {
+		total += n
+		s.Counts[i] = total
+	}
+	return s
+}
@@ -0,0 +1,252 @@
+package deamon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/queueio/sentry/utils/outputs"
+)
+
+// Outlet publishes the events of a collector and forwards their states to
+// the registrar. A state is only handed over once the output acknowledged
+// its event and every event published before it, so the registry never
+// points past data which was not delivered.
+type Outlet struct {
+	sync.Mutex
+	registrar *Registrar
+	events    *eventCounter
+	pending   []*pending
+	outbox    []State // acknowledged states not yet sent to the registrar
+	sending   bool
+	drained   *sync.Cond
+}
+
+// pending is stored in event.Private of published events.
+type pending struct {
+	outlet   *Outlet
+	state    State
+	hasState bool
+	acked    bool
+}
+
+// Completed is called by the output once the event was delivered.
+func (p *pending) Completed() {
+	p.outlet.ack(p)
+}
+
+func NewOutlet(registrar *Registrar, events *eventCounter) *Outlet {
+	o := &Outlet{
+		registrar: registrar,
+		events:    events,
+	}
+	o.drained = sync.NewCond(&o.Mutex)
+	return o
+}
+
+// Publish hands the event of data to the publisher. Data without an event
+// only carries a state update, which is queued behind the pending events.
+func (o *Outlet) Publish(publisher outputs.Publisher, data *Data) error {
+	p := o.track(data.GetState(), data.HasState())
+	if !data.HasEvent() {
+		o.ack(p)
+		return nil
+	}
+
+	e := data.GetEvent()
+	e.Private = p
+	if err := publisher.Publish(e); err != nil {
+		o.cancel(p)
+		return err
+	}
+	return nil
+}
+
+// Update queues a state which is not bound to an event. It reaches the
+// registrar as soon as all events published before were acknowledged.
+func (o *Outlet) Update(state State) {
+	if o == nil {
+		return
+	}
+	o.ack(o.track(state, true))
+}
+
+func (o *Outlet) track(state State, hasState bool) *pending {
+	o.Lock()
+	defer o.Unlock()
+
+	p := &pending{outlet: o, state: state, hasState: hasState}
+	o.pending = append(o.pending, p)
+	o.events.Add(1)
+	return p
+}
+
+func (o *Outlet) ack(p *pending) {
+	o.Lock()
+	defer o.Unlock()
+
+	p.acked = true
+
+	var states []State
+	n, stateless := 0, 0
+	for ; n < len(o.pending) && o.pending[n].acked; n++ {
+		if o.pending[n].hasState {
+			states = append(states, o.pending[n].state)
+		} else {
+			stateless++
+		}
+	}
+	o.pending = o.pending[n:]
+
+	// states without registrar are finished as well, they never get written
+	if o.registrar == nil {
+		stateless += len(states)
+		states = nil
+	}
+	o.events.Done(stateless)
+
+	// Sent by a single goroutine per outlet, so the states reach the
+	// registrar in order without blocking the publishers on registry I/O.
+	o.outbox = append(o.outbox, states...)
+	if len(o.outbox) > 0 && !o.sending {
+		o.sending = true
+		go o.send()
+	}
+	o.signal()
+}
+
+// send hands the acknowledged states to the registrar until none are left.
+func (o *Outlet) send() {
+	for {
+		o.Lock()
+		states := o.outbox
+		o.outbox = nil
+		if len(states) == 0 {
+			o.sending = false
+			o.signal()
+			o.Unlock()
+			return
+		}
+		o.Unlock()
+
+		// states the stopped registrar will never write are done as well
+		if !o.registrar.send(states) {
+			o.events.Done(len(states))
+		}
+	}
+}
+
+// signal wakes up Wait once all events were acknowledged and their states
+// handed to the registrar. Must be called with the lock held.
+func (o *Outlet) signal() {
+	if len(o.pending) == 0 && len(o.outbox) == 0 && !o.sending {
+		o.drained.Broadcast()
+	}
+}
+
+// Wait blocks until all events published were acknowledged and their states
+// handed to the registrar, or the timeout passed. It reports whether the
+// outlet was drained.
+func (o *Outlet) Wait(timeout time.Duration) bool {
+	timer := time.AfterFunc(timeout, func() {
+		o.Lock()
+		o.drained.Broadcast()
+		o.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	o.Lock()
+	defer o.Unlock()
+	for len(o.pending) > 0 || len(o.outbox) > 0 || o.sending {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		o.drained.Wait()
+	}
+	return true
+}
+
+// cancel removes an event the output refused, it will never be acknowledged.
+func (o *Outlet) cancel(p *pending) {
+	o.Lock()
+	for i, q := range o.pending {
+		if q == p {
+			o.pending = append(o.pending[:i], o.pending[i+1:]...)
+			o.events.Done(1)
+			break
+		}
+	}
+	o.signal()
+	o.Unlock()
+}
+
+// eventCounter keeps track of the events which were published but not yet
+// acknowledged and written to the registry. Unlike a WaitGroup, events may
+// be added while another goroutine waits.
+type eventCounter struct {
+	sync.Mutex
+	count int
+	zero  *sync.Cond
+}
+
+func newEventCounter() *eventCounter {
+	c := &eventCounter{}
+	c.zero = sync.NewCond(&c.Mutex)
+	return c
+}
+
+func (c *eventCounter) Add(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+
+	c.Lock()
+	c.count += n
+	c.Unlock()
+}
+
+func (c *eventCounter) Done(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+
+	c.Lock()
+	c.count -= n
+	if c.count <= 0 {
+		c.count = 0
+		c.zero.Broadcast()
+	}
+	c.Unlock()
+}
+
+// Wait blocks until all events are done, or the timeout passed if it is
+// positive. It reports whether all events are done.
+func (c *eventCounter) Wait(timeout time.Duration) bool {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+		timer := time.AfterFunc(timeout, func() {
+			c.Lock()
+			c.zero.Broadcast()
+			c.Unlock()
+		})
+		defer timer.Stop()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	for c.count > 0 {
+		if timeout > 0 && !time.Now().Before(deadline) {
+			return false
+		}
+		c.zero.Wait()
+	}
+	return true
+}
+
+// Published is called by the registrar once n states were written.
+func (c *eventCounter) Published(n int) bool {
+	c.Done(n)
+	return true
+}
@@ -10,10 +10,19 @@ import (
 
 	"github.com/queueio/sentry/utils/paths"
 	"github.com/queueio/sentry/utils/log"
+	"github.com/queueio/sentry/components/scribe/metric"
+)
+
+var (
+	registrarStates  = metric.NewGauge("registrar_states", "States kept by the registrar.", nil)
+	registrarUpdates = metric.NewCounter("registrar_updates_total", "State updates processed by the registrar.", nil)
+	registrarWrites  = metric.NewCounter("registrar_writes_total", "Writes of the registrar file.", nil)
+	registrarErrors  = metric.NewCounter("registrar_write_errors_total", "Failed writes of the registrar file.", nil)
 )
 
 type Registrar struct {
 	Channel      chan []State
+	sync         chan chan struct{}
 	out          successLogger
 	done         chan struct{}
 	registrarFile  string // Path to the Registrar File
@@ -34,6 +43,7 @@ func newRegistrar(registrarFile string, flushTimeout time.Duration, out successL
 		done:         make(chan struct{}),
 		states:       NewStates(),
 		Channel:      make(chan []State, 1),
+		sync:         make(chan chan struct{}),
 		flushTimeout: flushTimeout,
 		out:          out,
 		wg:           sync.WaitGroup{},
@@ -137,6 +147,16 @@ func (r *Registrar) Run() {
 		flushC <-chan time.Time
 	)
 
+	handle := func(states []State) {
+		r.onEvents(states)
+		if r.flushTimeout <= 0 {
+			r.flushRegistrar()
+		} else if flushC == nil {
+			timer = time.NewTimer(r.flushTimeout)
+			flushC = timer.C
+		}
+	}
+
 	for {
 		select {
 		case <-r.done:
@@ -147,13 +167,18 @@ func (r *Registrar) Run() {
 			timer.Stop()
 			r.flushRegistrar()
 		case states := <-r.Channel:
-			r.onEvents(states)
-			if r.flushTimeout <= 0 {
-				r.flushRegistrar()
-			} else if flushC == nil {
-				timer = time.NewTimer(r.flushTimeout)
-				flushC = timer.C
+			handle(states)
+		case synced := <-r.sync:
+			// states sent before Sync was called may still wait in the channel
+			for pending := true; pending; {
+				select {
+				case states := <-r.Channel:
+					handle(states)
+				default:
+					pending = false
+				}
 			}
+			close(synced)
 		}
 	}
 }
@@ -165,6 +190,8 @@ func (r *Registrar) onEvents(states []State) {
 	cleanedStates := r.states.Cleanup()
 
 	r.bufferedStateUpdates += len(states)
+	registrarUpdates.Add(uint64(len(states)))
+	registrarStates.Set(int64(r.states.Count()))
 
 	log.Debug("registrar",
 		"Registrar states cleaned up. Before: %d, After: %d",
@@ -179,6 +206,33 @@ func (r *Registrar) processEventStates(states []State) {
 	}
 }
 
+// send hands states to the registrar. States sent after the registrar was
+// stopped are dropped.
+func (r *Registrar) send(states []State) bool {
+	select {
+	case <-r.done:
+		return false
+	case r.Channel <- states:
+		return true
+	}
+}
+
+// Sync blocks until the states sent before were applied, so GetStates
+// returns them.
+func (r *Registrar) Sync() {
+	synced := make(chan struct{})
+	select {
+	case <-r.done:
+		return
+	case r.sync <- synced:
+	}
+
+	select {
+	case <-r.done:
+	case <-synced:
+	}
+}
+
 func (r *Registrar) Stop() {
 	log.Info("Stopping Registrar")
 	close(r.done)
@@ -186,7 +240,9 @@ func (r *Registrar) Stop() {
 }
 
 func (r *Registrar) flushRegistrar() {
+	registrarWrites.Inc()
 	if err := r.writeRegistrar(); err != nil {
+		registrarErrors.Inc()
 		log.Err("Writing of registrar returned error: %v. Continuing...", err)
 	}
 
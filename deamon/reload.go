@@ -2,9 +2,11 @@ package deamon
 
 import (
 	"os"
+	"errors"
 	"fmt"
 	"syscall"
 	"sync"
+	"time"
 	"os/signal"
 	"encoding/json"
 
@@ -19,6 +21,8 @@ import (
 
 var reloadDebug = log.MakeDebug("reload")
 
+var errStopped = errors.New("inputs stopped")
+
 type RunnerFactory interface {
 	Create(config *config.Config) (Runner, error)
 }
@@ -28,6 +32,22 @@ type Runner interface {
 	Stop()
 }
 
+// flusher is implemented by runners whose final states reach the registrar
+// only after they were stopped.
+type flusher interface {
+	Flush(timeout time.Duration) bool
+}
+
+// syncer is implemented by runner factories which can wait until the states
+// handed to the registrar were applied.
+type syncer interface {
+	Sync()
+}
+
+// stopFlushTimeout bounds the wait for the final states of stopped runners, it
+// only passes if the output does not acknowledge their events.
+const stopFlushTimeout = 30 * time.Second
+
 type Reload struct {
 	module    *Module
 	config    *Config
@@ -36,6 +56,12 @@ type Reload struct {
 	runner     RunnerFactory
 	configs []*config.Config
 
+	// inputs are managed through the api by their name, the reload lock
+	// serializes them with the reloads from the queue
+	inputs     map[string]*config.Config
+	reloadLock sync.Mutex
+	stopped    bool
+
 	qConfig   *queue.Config
 	consumer  *queue.Consumer
 }
@@ -77,7 +103,9 @@ func newReload(config *Config, info component.Info) (*Reload, error) {
 }
 
 func (r *Reload) Run(runner RunnerFactory) {
-	r.runner = runner
+	if r.runner == nil {
+		r.runner = runner
+	}
 	termChan := make(chan os.Signal, 1)
 	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -122,38 +150,54 @@ func (r *Reload) HandleMessage(message *queue.Message) error {
 
 func reloadModules(r *Reload, stream []byte) error {
 	var err error
-	config, err := config.LoadStream(stream)
+	cfg, err := config.LoadStream(stream)
 	if err != nil {
 		return err
 	}
 
-	if err := config.Unpack(r.configs); err != nil {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	if err := cfg.Unpack(&r.configs); err != nil {
 		log.Err("config unpack error: %s", err.Error())
 		return err
 	}
 
 	reloadDebug("Number of module configs found: %v", len(r.configs))
 
-	startList := map[uint64]Runner{}
-	stopList := r.module.CopyList()
+	// configs no runner can be created of were logged, delivering the
+	// message again would not create them either
+	r.apply()
+	return nil
+}
 
-	for _, c := range r.configs {
+// apply starts the modules of new configs and stops the ones whose config
+// is gone, modules are told apart by the hash of their config. The configs
+// from the queue and from the api are applied together, so neither stops the
+// modules of the other. It returns the errors of the configs no runner could
+// be created of by their hash.
+func (r *Reload) apply() map[uint64]error {
+	if r.stopped {
+		return nil
+	}
 
-		if !c.Enabled() {
-			continue
-		}
+	configs := append([]*config.Config{}, r.configs...)
+	for _, c := range r.inputs {
+		configs = append(configs, c)
+	}
 
-		rawCfg := map[string]interface{}{}
-		err := c.Unpack(rawCfg)
+	startList := map[uint64]*config.Config{}
+	stopList := r.module.CopyList()
 
-		if err != nil {
-			log.Err("Unable to unpack config file due to error: %v", err)
+	for _, c := range configs {
+
+		if !c.Enabled() {
 			continue
 		}
 
-		hash, err := hashstructure.Hash(rawCfg, nil)
+		hash, err := configHash(c)
 		if err != nil {
-			reloadDebug("Unable to generate hash for config file %v due to error: %v", c, err)
+			log.Err("Unable to hash config due to error: %v", err)
 			continue
 		}
 
@@ -163,20 +207,161 @@ func reloadModules(r *Reload, stream []byte) error {
 		// As module already exist, it must be removed from the stop list and not started
 		if !r.module.Has(hash) {
 			reloadDebug("Add module to startlist: %v", hash)
-			runner, err := r.runner.Create(c)
-			if err != nil {
-				log.Err("Unable to create runner due to error: %v", err)
-				continue
-			}
-			startList[hash] = runner
+			startList[hash] = c
 		}
 	}
 
+	// Runners are stopped before new ones are created, and their final
+	// states applied by the registrar before the new runners load them.
 	r.stopRunners(stopList)
-	r.startRunners(startList)
+	if s, ok := r.runner.(syncer); ok && len(stopList) > 0 {
+		s.Sync()
+	}
+	runners, errs := r.createRunners(startList)
+	r.startRunners(runners)
+	return errs
+}
+
+func configHash(c *config.Config) (uint64, error) {
+	rawCfg := map[string]interface{}{}
+	if err := c.Unpack(&rawCfg); err != nil {
+		return 0, err
+	}
+	return hashstructure.Hash(rawCfg, nil)
+}
+
+// validationError is returned for configs no runner can be created of.
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string {
+	return e.err.Error()
+}
+
+// validate checks the config without creating a runner, so errors reach the
+// caller before any module is stopped. Runners are only created once the
+// ones they replace were stopped and their states applied.
+func (r *Reload) validate(c *config.Config) error {
+	if _, err := configHash(c); err != nil {
+		return validationError{err}
+	}
+
+	if err := Validate(c); err != nil {
+		return validationError{err}
+	}
 	return nil
 }
 
+// PutInput starts the input of the config, replacing the input of the same
+// name started through the api before. It reports whether an input was
+// replaced.
+func (r *Reload) PutInput(name string, c *config.Config) (bool, error) {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	return r.setInput(name, c, true)
+}
+
+// CreateInput starts the input of the config unless an input of the same
+// name was started through the api before. It reports whether the input was
+// created.
+func (r *Reload) CreateInput(name string, c *config.Config) (bool, error) {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	exists, err := r.setInput(name, c, false)
+	return !exists && err == nil, err
+}
+
+// setInput starts the input of the config, an input of the same name is only
+// replaced if replace is set. It reports whether an input of the name
+// existed. The reload lock must be held.
+func (r *Reload) setInput(name string, c *config.Config, replace bool) (bool, error) {
+	if r.stopped {
+		return false, errStopped
+	}
+
+	if err := r.validate(c); err != nil {
+		return false, err
+	}
+
+	if r.inputs == nil {
+		r.inputs = map[string]*config.Config{}
+	}
+
+	previous, exists := r.inputs[name]
+	if exists && !replace {
+		return true, nil
+	}
+
+	// validate made sure the config hashes
+	hash, _ := configHash(c)
+	r.inputs[name] = c
+	if err, ok := r.apply()[hash]; ok {
+		// a config no runner can be created of is not kept, so it neither
+		// blocks a retry nor fails every later reload
+		if exists {
+			r.inputs[name] = previous
+		} else {
+			delete(r.inputs, name)
+		}
+		r.apply()
+		return exists, err
+	}
+	return exists, nil
+}
+
+// DeleteInput stops the input of the name started through the api.
+func (r *Reload) DeleteInput(name string) (bool, error) {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	if _, ok := r.inputs[name]; !ok {
+		return false, nil
+	}
+
+	delete(r.inputs, name)
+	r.apply()
+	return true, nil
+}
+
+// stopModules stops the runners of all modules and waits for their final
+// states, no modules are started afterwards.
+func (r *Reload) stopModules() {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	r.stopped = true
+	r.stopRunners(r.module.CopyList())
+}
+
+// HasInput tells whether an input of the name was started through the api.
+func (r *Reload) HasInput(name string) bool {
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+
+	_, ok := r.inputs[name]
+	return ok
+}
+
+// createRunners creates the runners it can and returns the errors of the
+// others by their hash.
+func (r *Reload) createRunners(configs map[uint64]*config.Config) (map[uint64]Runner, map[uint64]error) {
+	errs := map[uint64]error{}
+	list := map[uint64]Runner{}
+	for hash, c := range configs {
+		runner, err := r.runner.Create(c)
+		if err != nil {
+			log.Err("Unable to create runner due to error: %v", err)
+			errs[hash] = err
+			continue
+		}
+		list[hash] = runner
+	}
+	return list, errs
+}
+
 // delivery 10 failure message
 func (r *Reload) LogFailedMessage(message *queue.Message) {
 	log.Err("%v", message.Body)
@@ -213,6 +398,9 @@ func (r *Reload) stopRunners(list map[uint64]Runner) {
 			}()
 
 			run.Stop()
+			if f, ok := run.(flusher); ok && !f.Flush(stopFlushTimeout) {
+				log.Warn("Final states of runner %v not acknowledged within %v", h, stopFlushTimeout)
+			}
 			r.module.Remove(h)
 		}(hash, runner)
 	}
@@ -0,0 +1,150 @@
+// +build !integration
+
+package deamon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/queue"
+)
+
+type testRunner struct {
+	running bool
+}
+
+func (r *testRunner) Start() { r.running = true }
+func (r *testRunner) Stop()  { r.running = false }
+
+// testRunnerFactory fails to create runners of configs with fail set.
+type testRunnerFactory struct {
+	runners []*testRunner
+}
+
+func (f *testRunnerFactory) Create(c *config.Config) (Runner, error) {
+	var v struct {
+		Fail bool `config:"fail"`
+	}
+	if err := c.Unpack(&v); err != nil {
+		return nil, err
+	}
+	if v.Fail {
+		return nil, errors.New("create failed")
+	}
+
+	runner := &testRunner{}
+	f.runners = append(f.runners, runner)
+	return runner, nil
+}
+
+func (f *testRunnerFactory) running() int {
+	n := 0
+	for _, r := range f.runners {
+		if r.running {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestInput(t *testing.T, stream string) *config.Config {
+	c, err := config.LoadStream([]byte("type: reloadtest\n" + stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newTestReload() (*Reload, *testRunnerFactory) {
+	// registered by the first test only
+	Register("reloadtest", func(*config.Config, queue.Handler, Context) (Collector, error) {
+		return nil, nil
+	})
+
+	factory := &testRunnerFactory{}
+	return &Reload{module: NewModule(), runner: factory}, factory
+}
+
+func TestPutInputCreateFails(t *testing.T) {
+	r, factory := newTestReload()
+
+	replaced, err := r.PutInput("a", newTestInput(t, "path: a\n"))
+	assert.NoError(t, err)
+	assert.False(t, replaced)
+	assert.Equal(t, 1, factory.running())
+
+	// a new input which fails is not kept
+	_, err = r.PutInput("b", newTestInput(t, "fail: true\n"))
+	assert.Error(t, err)
+	assert.False(t, r.HasInput("b"))
+	assert.Equal(t, 1, factory.running())
+
+	_, err = r.PutInput("b", newTestInput(t, "path: b\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, factory.running())
+
+	// a replacement which fails restores the input it replaced
+	replaced, err = r.PutInput("a", newTestInput(t, "path: a\nfail: true\n"))
+	assert.Error(t, err)
+	assert.True(t, replaced)
+	assert.True(t, r.HasInput("a"))
+	assert.Equal(t, 2, factory.running())
+
+	var v struct {
+		Path string `config:"path"`
+	}
+	assert.NoError(t, r.inputs["a"].Unpack(&v))
+	assert.Equal(t, "a", v.Path)
+}
+
+func TestPutInputQueueConfigFails(t *testing.T) {
+	r, factory := newTestReload()
+	r.configs = []*config.Config{newTestInput(t, "fail: true\n")}
+
+	replaced, err := r.PutInput("a", newTestInput(t, "path: a\n"))
+	assert.NoError(t, err)
+	assert.False(t, replaced)
+	assert.True(t, r.HasInput("a"))
+	assert.Equal(t, 1, factory.running())
+
+	deleted, err := r.DeleteInput("a")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	assert.Equal(t, 0, factory.running())
+}
+
+func TestCreateInputExists(t *testing.T) {
+	r, factory := newTestReload()
+
+	created, err := r.CreateInput("a", newTestInput(t, "path: a\n"))
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = r.CreateInput("a", newTestInput(t, "path: b\n"))
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 1, factory.running())
+
+	var v struct {
+		Path string `config:"path"`
+	}
+	assert.NoError(t, r.inputs["a"].Unpack(&v))
+	assert.Equal(t, "a", v.Path)
+}
+
+func TestStopModules(t *testing.T) {
+	r, factory := newTestReload()
+
+	_, err := r.PutInput("a", newTestInput(t, "path: a\n"))
+	assert.NoError(t, err)
+
+	r.stopModules()
+	assert.Equal(t, 0, factory.running())
+
+	_, err = r.PutInput("b", newTestInput(t, "path: b\n"))
+	assert.Equal(t, errStopped, err)
+	assert.Equal(t, 0, factory.running())
+}
@@ -17,16 +17,47 @@ type Collector interface {
 	Stop()
 }
 
+// Waiter is implemented by collectors which finish on their own, like stdin.
+type Waiter interface {
+	Wait()
+}
+
+// Inspector is implemented by collectors which can describe their scanners.
+type Inspector interface {
+	Scanners() []ScannerStatus
+}
+
+// ScannerStatus describes a running scanner and how far it is behind the end
+// of its file.
+type ScannerStatus struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Inode  uint64 `json:"inode"`
+	Device uint64 `json:"device"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Lag    int64  `json:"lag"`
+}
+
+// RobotStatus describes a running robot.
+type RobotStatus struct {
+	ID       uint64          `json:"id"`
+	Name     string          `json:"name,omitempty"`
+	Type     string          `json:"type"`
+	Scanners []ScannerStatus `json:"scanners"`
+}
+
 type Robot struct {
 	config     InputConfig
 	collector  Collector
+	outlet    *Outlet
 	done       chan struct{}
 	wg        *sync.WaitGroup
 	ID         uint64
 	sentryDone chan struct{}
 }
 
-func NewRobot(conf *cfg.Config, handler queue.Handler, sentryDone chan struct{}, states []State) (*Robot, error) {
+func NewRobot(conf *cfg.Config, handler queue.Handler, sentryDone chan struct{}, registrar *Registrar, events *eventCounter) (*Robot, error) {
 	robot := &Robot{
 		config:     defaultConfig,
 		wg:         &sync.WaitGroup{},
@@ -55,8 +86,10 @@ func NewRobot(conf *cfg.Config, handler queue.Handler, sentryDone chan struct{},
 		return robot, err
 	}
 
+	robot.outlet = NewOutlet(registrar, events)
 	context := Context{
-		States:     states,
+		States:     registrar.GetStates(),
+		Outlet:     robot.outlet,
 		Done:       robot.done,
 		SentryDone: robot.sentryDone,
 	}
@@ -98,11 +131,45 @@ func (r *Robot) Run() {
 	}
 }
 
+// Wait blocks until the collector finished its work. It returns at once if
+// the collector keeps running until it is stopped.
+func (r *Robot) Wait() {
+	if w, ok := r.collector.(Waiter); ok {
+		w.Wait()
+	}
+}
+
+// Status describes the robot and the scanners of its collector.
+func (r *Robot) Status() RobotStatus {
+	status := RobotStatus{
+		ID:       r.ID,
+		Name:     r.config.Name,
+		Type:     r.config.Type,
+		Scanners: []ScannerStatus{},
+	}
+
+	if i, ok := r.collector.(Inspector); ok {
+		if scanners := i.Scanners(); scanners != nil {
+			status.Scanners = scanners
+		}
+	}
+	return status
+}
+
 func (r *Robot) Stop() {
 	close(r.done)
 	r.wg.Wait()
 }
 
+// Flush waits until the states of the events the robot published, including
+// the final states of a stopped robot, were handed to the registrar.
+func (r *Robot) Flush(timeout time.Duration) bool {
+	if r.outlet == nil {
+		return true
+	}
+	return r.outlet.Wait(timeout)
+}
+
 func (r *Robot) stop() {
 	log.Info("Stopping Robot: %d", r.ID)
 	r.collector.Stop()
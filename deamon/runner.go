@@ -8,22 +8,30 @@ import (
 type runner struct {
 	handler     queue.Handler
 	registrar    *Registrar
+	events      *eventCounter
 	sentryDone  chan struct{}
 }
 
-func newRunner(handler queue.Handler, registrar *Registrar, sentryDone chan struct{}) *runner {
+func newRunner(handler queue.Handler, registrar *Registrar, events *eventCounter, sentryDone chan struct{}) *runner {
 	return &runner{
 		handler:    handler,
 		registrar:    registrar,
+		events:     events,
 		sentryDone: sentryDone,
 	}
 }
 
 func (r *runner) Create(c *config.Config) (Runner, error) {
-	robot, err := NewRobot(c, r.handler, r.sentryDone, r.registrar.GetStates())
+	robot, err := NewRobot(c, r.handler, r.sentryDone, r.registrar, r.events)
 	if err != nil {
 		return robot, err
 	}
 
 	return robot, nil
 }
+
+
+// Sync blocks until the states handed to the registrar were applied.
+func (r *runner) Sync() {
+	r.registrar.Sync()
+}
\ No newline at end of file
@@ -0,0 +1,103 @@
+package stdin
+
+import (
+	"fmt"
+
+	"github.com/queueio/sentry/utils/log"
+cfg	"github.com/queueio/sentry/utils/config"
+	"github.com/queueio/sentry/utils/queue"
+	"github.com/queueio/sentry/utils/outputs/route"
+
+	"github.com/queueio/sentry/components/scribe"
+	input "github.com/queueio/sentry/components/scribe/log"
+)
+
+func init() {
+	err := scribe.Register(scribe.StdinType, New)
+	if err != nil {
+		panic(err)
+	}
+
+	err = scribe.RegisterValidator(scribe.StdinType, Validate)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Validate checks the config of a stdin input without creating its collector.
+func Validate(cfg *cfg.Config) error {
+	return cfg.Unpack(&config{})
+}
+
+type config struct {
+	Name    string         `config:"name" validate:"required"`
+	Outputs []route.Config `config:"outputs"`
+}
+
+// Collector reads events from stdin with a single scanner. Stdin has no
+// state, so nothing is written to the registry and the collector finishes
+// as soon as stdin is closed.
+type Collector struct {
+	scanner  *input.Scanner
+	started  bool
+	finished chan struct{}
+}
+
+func New(cfg *cfg.Config, handler queue.Handler, context scribe.Context) (scribe.Collector, error) {
+	config := config{}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	c := &Collector{
+		finished: make(chan struct{}),
+	}
+
+	output, err := route.Publisher(config.Name, handler, config.Outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	c.scanner, err = input.NewScanner(cfg, scribe.State{Source: "-"}, scribe.NewStates(),
+		context.Outlet, output)
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing stdin scanner: %v", err)
+	}
+
+	return c, nil
+}
+
+func (c *Collector) Run() {
+	if c.started {
+		return
+	}
+	c.started = true
+
+	if err := c.scanner.Setup(); err != nil {
+		log.Err("Error setting up stdin scanner: %v", err)
+		close(c.finished)
+		return
+	}
+
+	go func() {
+		defer close(c.finished)
+
+		if err := c.scanner.Run(); err != nil {
+			log.Err("Stdin scanner stopped with error: %v", err)
+		}
+	}()
+}
+
+// Wait blocks until stdin reached EOF and the scanner has stopped.
+func (c *Collector) Wait() {
+	<-c.finished
+}
+
+// Scanners describes the stdin scanner.
+func (c *Collector) Scanners() []scribe.ScannerStatus {
+	return []scribe.ScannerStatus{c.scanner.Status()}
+}
+
+func (c *Collector) Stop() {
+	c.scanner.Stop()
+}
@@ -3,12 +3,13 @@ package main
 import (
 	"os"
 
-	"github.com/agenxio/agenx/deamon"
-// _	"github.com/queueio/sentry/components/scribe/log"
+	"github.com/queueio/sentry/components/scribe"
+	_ "github.com/queueio/sentry/components/scribe/log"
+	_ "github.com/queueio/sentry/components/scribe/stdin"
 )
 
 func main() {
-	if err := deamon.Command.Execute(); err != nil {
+	if err := scribe.Command.Execute(); err != nil {
 		os.Exit(1)
 	}
 }